@@ -3,6 +3,8 @@ package expressions
 import (
 	"fmt"
 	"strconv"
+	"strings"
+	"sync"
 )
 
 const (
@@ -15,6 +17,13 @@ const (
 	Macro
 	Number
 	Nil
+	Promise
+	Vector
+	EOF
+	Environment
+	Parameter
+	Generator
+	Record
 )
 
 type ExprError struct {
@@ -29,9 +38,14 @@ func (ee *ExprError) Error() string {
 	return ee.message
 }
 
+// Vars is a scope of symbols. Arguments of closure's call are kept in slice in order of closure's parameters, other
+// symbols of the scope are kept in map that is created by the first definition
 type Vars struct {
 	CurSymbols map[string]*Expr
 	Parent     *Vars
+
+	params []string
+	args   []*Expr
 }
 
 func NewRootVars() *Vars {
@@ -52,19 +66,87 @@ func (v *Vars) IsRoot() bool {
 	return v.Parent == nil
 }
 
-func varsDebug(vars map[string]*Expr) string {
+// param returns index of argument of the symbol or -1
+func (v *Vars) param(name string) int {
+	for i := range v.args {
+		if v.params[i] == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Get returns value of the symbol defined in the scope
+func (v *Vars) Get(name string) (*Expr, bool) {
+	if i := v.param(name); i != -1 {
+		return v.args[i], true
+	}
+
+	expr, ok := v.CurSymbols[name]
+	return expr, ok
+}
+
+// Set defines the symbol in the scope or changes its value
+func (v *Vars) Set(name string, expr *Expr) {
+	if i := v.param(name); i != -1 {
+		v.args[i] = expr
+		return
+	}
+
+	if v.CurSymbols == nil {
+		v.CurSymbols = map[string]*Expr{}
+	}
+
+	v.CurSymbols[name] = expr
+}
+
+// Delete removes the symbol from the scope
+func (v *Vars) Delete(name string) {
+	if i := v.param(name); i != -1 {
+		// arguments are moved to map, so the rest arguments keep their names
+		params, args := v.params, v.args
+		v.params, v.args = nil, nil
+		for j, arg := range args {
+			if j != i {
+				v.Set(params[j], arg)
+			}
+		}
+
+		return
+	}
+
+	delete(v.CurSymbols, name)
+}
+
+// Params returns names of arguments of closure's call kept by the scope
+func (v *Vars) Params() []string {
+	return v.params[:len(v.args)]
+}
+
+// Names returns names of symbols defined in the scope
+func (v *Vars) Names() []string {
+	names := append([]string{}, v.Params()...)
+	for name := range v.CurSymbols {
+		names = append(names, name)
+	}
+
+	return names
+}
+
+func varsDebug(names []string) string {
 	res := "( "
-	for k, _ := range vars {
+	for _, k := range names {
 		res += k + /*" : " + v.ToString() +*/ " "
 	}
 	return res + ")"
 }
 
 func (v *Vars) Debug() {
-	str := fmt.Sprintf("%s ", varsDebug(v.CurSymbols))
+	str := fmt.Sprintf("%s ", varsDebug(v.Names()))
 	cur := v.Parent
 	for cur != nil {
-		str += fmt.Sprintf("-> %s ", varsDebug(cur.CurSymbols))
+		str += fmt.Sprintf("-> %s ", varsDebug(cur.Names()))
 		cur = cur.Parent
 	}
 	fmt.Println(str)
@@ -73,11 +155,23 @@ func (v *Vars) Debug() {
 type variable struct {
 	name               string
 	calculatedForMacro bool
+
+	optional     bool
+	defaultValue *Expr
 }
 
 type closureVars struct {
 	variableNumber bool
 	vars           []variable
+	// names are names of vars, they are shared by scopes of calls
+	names []string
+}
+
+func (cv *closureVars) setNames() {
+	cv.names = make([]string, len(cv.vars))
+	for i, v := range cv.vars {
+		cv.names[i] = v.name
+	}
 }
 
 type trace struct {
@@ -89,6 +183,8 @@ type Expr struct {
 	Type               int
 	String             string
 	Number             float64
+	Vector             []*Expr
+	RecordType         *RecordType
 	Res, car, cdr      *Expr
 	CalculatedForMacro bool
 
@@ -98,6 +194,8 @@ type Expr struct {
 		f   *Expr
 		pos int
 	}
+	// backtrace is names of functions which calls were interrupted by fatal, the innermost first
+	backtrace []string
 }
 
 func (e *Expr) DebugString() string {
@@ -116,6 +214,31 @@ func (e *Expr) DebugString() string {
 		return "Macro" + fmt.Sprintf("%v", e.Vars.vars) + e.cdr.ToString()
 	case Nil:
 		return "Nil"
+	case Promise:
+		return "Promise(" + e.car.ToString() + ")"
+	case EOF:
+		return "EOF"
+	case Environment:
+		return "Environment"
+	case Parameter:
+		return "Parameter(" + e.car.DebugString() + ")"
+	case Generator:
+		return "Generator"
+	case Record:
+		res := "Record(" + e.RecordType.Name
+		for _, elem := range e.Vector {
+			res += " " + elem.DebugString()
+		}
+		return res + ")"
+	case Vector:
+		res := "Vector("
+		for i, elem := range e.Vector {
+			if i > 0 {
+				res += " "
+			}
+			res += elem.DebugString()
+		}
+		return res + ")"
 	case Pair:
 		return fmt.Sprintf("( %s . %s )", e.car.DebugString(), e.cdr.DebugString())
 	default:
@@ -139,6 +262,31 @@ func (e *Expr) ToString() string {
 		return "Macro" + fmt.Sprintf("%v", e.Vars.vars)
 	case Nil:
 		return "nil"
+	case Promise:
+		return "Promise"
+	case EOF:
+		return "#<eof>"
+	case Environment:
+		return "Environment"
+	case Parameter:
+		return "Parameter"
+	case Generator:
+		return "Generator"
+	case Record:
+		res := "#<" + e.RecordType.Name
+		for _, elem := range e.Vector {
+			res += " " + elem.ToString()
+		}
+		return res + ">"
+	case Vector:
+		res := "#("
+		for i, elem := range e.Vector {
+			if i > 0 {
+				res += " "
+			}
+			res += elem.ToString()
+		}
+		return res + ")"
 	case Pair:
 		res := "("
 		cur := e
@@ -157,11 +305,32 @@ func (e *Expr) ToString() string {
 	}
 }
 
+// PrettyString returns representation of expression where lists which don't fit in width are broken across lines
+func (e *Expr) PrettyString(width int) string {
+	return e.prettyString(0, width)
+}
+
+func (e *Expr) prettyString(indent, width int) string {
+	flat := e.ToString()
+	if e.Type != Pair || indent+len([]rune(flat)) <= width {
+		return flat
+	}
+
+	res := "(" + e.car.prettyString(indent+1, width)
+	for cur := e.cdr; cur.Type != Nil; cur = cur.cdr {
+		res += "\n" + strings.Repeat(" ", indent+2) + cur.car.prettyString(indent+2, width)
+	}
+	return res + ")"
+}
+
 func (e *Expr) StackTrace() string {
 	res := "FATAL: " + e.String + "\n"
 	for _, st := range e.stackTrace {
 		res += st.f.DebugString() + " [" + strconv.Itoa(st.pos) + "]\n"
 	}
+	for _, name := range e.backtrace {
+		res += "in " + name + "\n"
+	}
 	return res
 }
 
@@ -172,6 +341,27 @@ func NewSymbol(name string) *Expr {
 	}
 }
 
+// symbols is a table of interned symbols
+var symbols = struct {
+	sync.Mutex
+	table map[string]*Expr
+}{table: map[string]*Expr{}}
+
+// Intern returns the canonical symbol of the name. Symbols read by parser are interned, so equal symbols of program are
+// the same object. Interned symbol is shared and must not be changed
+func Intern(name string) *Expr {
+	symbols.Lock()
+	defer symbols.Unlock()
+
+	sym, ok := symbols.table[name]
+	if !ok {
+		sym = NewSymbol(name)
+		symbols.table[name] = sym
+	}
+
+	return sym
+}
+
 func NewFatal(tag string, res ...*Expr) *Expr {
 	fat := &Expr{
 		Type:   Fatal,
@@ -219,17 +409,31 @@ func NewClosure(args *Expr, body []*Expr, parentVars *Vars) *Expr {
 			vars:           []variable{{name: args.String}},
 		}
 	} else {
+		optional := false
 		for !args.IsNil() {
-			if args.Car().Type != Symbol {
+			arg := variable{name: args.Car().String, optional: optional, defaultValue: NewNil()}
+
+			if args.Car().Type == Symbol && args.Car().String == "#!optional" {
+				if optional {
+					return NewFatal("lambda: #!optional must be used once")
+				}
+
+				optional = true
+				args = args.Cdr()
+				continue
+			} else if optional && args.Car().Type == Pair && args.Car().Length() == 2 && args.Car().car.Type == Symbol {
+				arg.name = args.Car().car.String
+				arg.defaultValue = args.Car().cdr.car
+			} else if args.Car().Type != Symbol {
 				return NewFatal("lambda: all args must be a symbols")
 			}
 
-			if _, ok := exists[args.Car().String]; ok {
+			if _, ok := exists[arg.name]; ok {
 				return NewFatal("lambda: all args must be a different")
 			}
 
-			exists[args.Car().String] = struct{}{}
-			vars.vars = append(vars.vars, variable{name: args.Car().String})
+			exists[arg.name] = struct{}{}
+			vars.vars = append(vars.vars, arg)
 			args = args.Cdr()
 		}
 	}
@@ -238,6 +442,8 @@ func NewClosure(args *Expr, body []*Expr, parentVars *Vars) *Expr {
 		return NewFatal("lambda: nil body")
 	}
 
+	vars.setNames()
+
 	lambdaBody := NewNil()
 	for i := len(body) - 1; i >= 0; i-- {
 		lambdaBody = body[i].Cons(lambdaBody)
@@ -288,6 +494,8 @@ func NewMacro(args *Expr, body []*Expr, parentVars *Vars) *Expr {
 		return NewFatal("defmacro: nil body")
 	}
 
+	vars.setNames()
+
 	lambdaBody := NewNil()
 	for i := len(body) - 1; i >= 0; i-- {
 		lambdaBody = body[i].Cons(lambdaBody)
@@ -321,8 +529,10 @@ func (e *Expr) MacroExecMod() map[int]struct{} {
 }
 
 func (e *Expr) NewClosureVars(args []*Expr) (*Vars, error) {
-	vars := NewRootVars()
-	vars.Parent = e.ParentVars
+	vars := &Vars{
+		Parent: e.ParentVars,
+		params: e.Vars.names,
+	}
 
 	if e.Vars.variableNumber {
 		if len(e.Vars.vars) != 1 {
@@ -334,22 +544,141 @@ func (e *Expr) NewClosureVars(args []*Expr) (*Vars, error) {
 			argsList = args[i].Cons(argsList)
 		}
 
-		vars.CurSymbols[e.Vars.vars[0].name] = argsList
+		vars.args = []*Expr{argsList}
 	} else {
-		if len(e.Vars.vars) != len(args) {
+		required := 0
+		for _, v := range e.Vars.vars {
+			if !v.optional {
+				required++
+			}
+		}
+
+		if required == len(e.Vars.vars) && len(e.Vars.vars) != len(args) {
 			return nil, NewExprError(fmt.Sprintf("call: expected %d args, got %d args", len(e.Vars.vars), len(args)))
 		}
 
-		for i, v := range e.Vars.vars {
-			vars.CurSymbols[v.name] = args[i]
+		if len(args) < required || len(args) > len(e.Vars.vars) {
+			return nil, NewExprError(fmt.Sprintf("call: expected from %d to %d args, got %d args", required, len(e.Vars.vars), len(args)))
 		}
+
+		// omitted optional args are defined by body of closure
+		vars.args = args
 	}
 
 	return vars, nil
 }
 
-func (e *Expr) ClosureBody() *Expr {
-	return e.car.Cons(e.cdr)
+// AcceptsArgs reports whether closure can be called with given number of arguments
+func (e *Expr) AcceptsArgs(argsNum int) bool {
+	if e.Vars.variableNumber {
+		return true
+	}
+
+	required := 0
+	for _, v := range e.Vars.vars {
+		if !v.optional {
+			required++
+		}
+	}
+
+	return argsNum >= required && argsNum <= len(e.Vars.vars)
+}
+
+// ClosureBody returns body of closure which calculates default values of omitted optional args before
+func (e *Expr) ClosureBody(argsNum int) *Expr {
+	body := e.cdr
+	if !e.Vars.variableNumber {
+		for i := len(e.Vars.vars) - 1; i >= argsNum; i-- {
+			body = NewList(NewFunction("define"), NewSymbol(e.Vars.vars[i].name), e.Vars.vars[i].defaultValue).Cons(body)
+		}
+	}
+
+	return e.car.Cons(body)
+}
+
+// NewPromise returns promise to calculate expression in scope. Res of promise is nil until result is calculated
+func NewPromise(expr *Expr, parentVars *Vars) *Expr {
+	return &Expr{
+		Type:       Promise,
+		car:        expr,
+		ParentVars: parentVars,
+	}
+}
+
+// PromiseExpr returns expression that is calculated by promise
+func (e *Expr) PromiseExpr() *Expr {
+	return e.car
+}
+
+// NewVector returns vector of given elements. Elements are copied, so following changes of slice don't change the vector
+func NewVector(elems ...*Expr) *Expr {
+	return &Expr{
+		Type:   Vector,
+		Vector: append([]*Expr{}, elems...),
+	}
+}
+
+// RecordType is a type of records defined by define-record-type. Types are compared by identity, so types with the same
+// name are different
+type RecordType struct {
+	Name   string
+	Fields []string
+}
+
+// NewRecord returns record of the type with values of fields in order of fields of the type. Values are copied
+func NewRecord(recordType *RecordType, values []*Expr) *Expr {
+	return &Expr{
+		Type:       Record,
+		RecordType: recordType,
+		Vector:     append([]*Expr{}, values...),
+	}
+}
+
+// NewEnvironment returns environment as a value: scope that expressions can be calculated in later
+func NewEnvironment(vars *Vars) *Expr {
+	return &Expr{
+		Type:       Environment,
+		ParentVars: vars,
+	}
+}
+
+// NewParameter returns parameter object with the value. Value of parameter is changed for dynamic extent of parameterize
+func NewParameter(value *Expr) *Expr {
+	return &Expr{
+		Type: Parameter,
+		car:  value,
+	}
+}
+
+// ParameterValue returns current value of parameter
+func (e *Expr) ParameterValue() *Expr {
+	return e.car
+}
+
+// SetParameterValue changes current value of parameter
+func (e *Expr) SetParameterValue(value *Expr) {
+	e.car = value
+}
+
+// NewGenerator returns generator of values yielded by the closure without arguments. Res of generator is nil until the
+// closure returns
+func NewGenerator(thunk *Expr) *Expr {
+	return &Expr{
+		Type: Generator,
+		car:  thunk,
+	}
+}
+
+// GeneratorThunk returns closure which yields values of generator
+func (e *Expr) GeneratorThunk() *Expr {
+	return e.car
+}
+
+// NewEOF returns object that is returned by input functions at the end of input
+func NewEOF() *Expr {
+	return &Expr{
+		Type: EOF,
+	}
 }
 
 func NewNumber(num float64) *Expr {
@@ -360,10 +689,7 @@ func NewNumber(num float64) *Expr {
 }
 
 func NewT() *Expr {
-	return &Expr{
-		Type:   Symbol,
-		String: "T",
-	}
+	return Intern("T")
 }
 
 func NewNil() *Expr {
@@ -372,10 +698,49 @@ func NewNil() *Expr {
 	}
 }
 
+func NewList(exprs ...*Expr) *Expr {
+	list := NewNil()
+	for i := len(exprs) - 1; i >= 0; i-- {
+		list = exprs[i].Cons(list)
+	}
+
+	return list
+}
+
+func (e *Expr) ToSlice() ([]*Expr, bool) {
+	var res []*Expr
+
+	cur := e
+	for cur.Type == Pair {
+		res = append(res, cur.car)
+		cur = cur.cdr
+	}
+
+	return res, cur.Type == Nil
+}
+
 func (e *Expr) AddTrace(f *Expr, pos int) {
 	e.stackTrace = append(e.stackTrace, trace{f, pos})
 }
 
+// backtraceLimit is a maximum number of names in backtrace, the rest names are replaced by "..."
+const backtraceLimit = 10
+
+func (e *Expr) AddBacktrace(name string) {
+	if len(e.backtrace) == backtraceLimit {
+		name = "..."
+	} else if len(e.backtrace) > backtraceLimit {
+		return
+	}
+
+	e.backtrace = append(e.backtrace, name)
+}
+
+// Backtrace returns names of functions which calls were interrupted by fatal, the innermost first
+func (e *Expr) Backtrace() []string {
+	return append([]string{}, e.backtrace...)
+}
+
 func (e *Expr) Cons(cdr *Expr) *Expr {
 	if cdr.Type == Pair || cdr.Type == Nil {
 		return &Expr{
@@ -404,6 +769,28 @@ func (e *Expr) Cdr() *Expr {
 	return NewFatal("cdr: object must be pair: " + e.DebugString())
 }
 
+func (e *Expr) SetCar(car *Expr) *Expr {
+	if e.Type != Pair {
+		return NewFatal("set-car: object must be pair")
+	}
+
+	e.car = car
+	return e
+}
+
+func (e *Expr) SetCdr(cdr *Expr) *Expr {
+	if e.Type != Pair {
+		return NewFatal("set-cdr: object must be pair")
+	}
+
+	if cdr.Type != Pair && cdr.Type != Nil {
+		return NewFatal("set-cdr: cdr must be a pair or nil")
+	}
+
+	e.cdr = cdr
+	return e
+}
+
 func (e *Expr) Equal(e1 *Expr) bool {
 	if e == nil || e1 == nil {
 		return e == e1
@@ -413,6 +800,29 @@ func (e *Expr) Equal(e1 *Expr) bool {
 		return false
 	}
 
+	// interned symbols and shared structures are compared without walking them
+	if e == e1 {
+		return true
+	}
+
+	if e.Type == Promise || e.Type == Environment || e.Type == Parameter || e.Type == Generator {
+		return e == e1
+	}
+
+	if e.Type == Vector || e.Type == Record {
+		if e1.Type != e.Type || e1.RecordType != e.RecordType || len(e.Vector) != len(e1.Vector) {
+			return false
+		}
+
+		for i, elem := range e.Vector {
+			if !elem.Equal(e1.Vector[i]) {
+				return false
+			}
+		}
+
+		return true
+	}
+
 	return e.Type == e1.Type && (e.Type == Fatal || e.String == e1.String && e.Number == e1.Number && e.car.Equal(e1.car) && e.cdr.Equal(e1.cdr))
 }
 
@@ -1,11 +1,18 @@
 package interpreter
 
 import (
-	"bufio"
+	"bytes"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
+	"unicode"
 
 	ex "github.com/batrSens/LispXS/expressions"
 	"github.com/batrSens/LispXS/lexer"
@@ -21,27 +28,50 @@ const (
 	ModMacro
 )
 
+// ppWidth is a maximal width of line printed by pp
+const ppWidth = 40
+
+// maxSymbolLength is a maximal length of symbols built by functions, longer ones would exhaust memory of the host
+const maxSymbolLength = 1 << 24
+
 type Mod struct {
 	Type int
 	Exec map[int]struct{}
 	Old  *Mod
 }
 
+// dropArg removes from the stack argument which doesn't affect result of call, so long chains of arguments (e.g. of 'or'
+// and 'and') keep only the current result on the stack
+func dropArg(ir *interpreter) {
+	ir.dataStack.Pop()
+	ir.argsNum--
+}
+
 func modApply(ir *interpreter) bool {
 	switch ir.mod.Type {
 	case ModOr:
-		if ir.argsNum > 2 && !ir.dataStack.Last().IsNil() {
-			ir.dataStack.Push(ex.NewT())
-			return true
+		if ir.argsNum > 2 {
+			// after the first not nil result the rest arguments aren't calculated
+			if !ir.isFalse(ir.dataStack.Last()) {
+				ir.argsNum--
+				return true
+			}
+
+			dropArg(ir)
 		}
 	case ModAnd:
-		if ir.argsNum > 2 && ir.dataStack.Last().IsNil() {
-			ir.dataStack.Push(ex.NewNil())
-			return true
+		if ir.argsNum > 2 {
+			// after the first nil result the rest arguments aren't calculated
+			if ir.isFalse(ir.dataStack.Last()) {
+				ir.argsNum--
+				return true
+			}
+
+			dropArg(ir)
 		}
 	case ModIf:
-		if ir.argsNum == 3 && ir.dataStack.Last().IsNil() ||
-			ir.argsNum == 4 && !ir.dataStack.PreLast().IsNil() || ir.argsNum > 4 {
+		if ir.argsNum == 3 && ir.isFalse(ir.dataStack.Last()) ||
+			ir.argsNum == 4 && !ir.isFalse(ir.dataStack.PreLast()) || ir.argsNum > 4 {
 			ir.dataStack.Push(ex.NewNil())
 			return true
 		}
@@ -66,402 +96,3702 @@ func modApply(ir *interpreter) bool {
 type Func struct {
 	F   func(ir *interpreter, args []*ex.Expr) *ex.Expr
 	Mod *Mod
+
+	// Eval means that result of F is a code that is evaluated in place of the call
+	Eval bool
+}
+
+func quote(expr *ex.Expr) *ex.Expr {
+	return ex.NewList(ex.NewFunction("quote"), expr)
+}
+
+func begin(exprs ...*ex.Expr) *ex.Expr {
+	return ex.NewFunction("begin").Cons(ex.NewList(exprs...))
+}
+
+// given returns representation of argument which is not a number for errors, symbols are marked to not be mistaken for
+// numbers (e.g. '|2|)
+func given(expr *ex.Expr) string {
+	if expr.Type == ex.Symbol {
+		return "symbol " + expr.ToString()
+	}
+
+	return expr.ToString()
+}
+
+func natural(name string, expr *ex.Expr) (int, *ex.Expr) {
+	if expr.Type != ex.Number || expr.Number < 0 || expr.Number != float64(int(expr.Number)) {
+		return 0, ex.NewFatal(name + ": expected non-negative integer, given " + given(expr))
+	}
+
+	return int(expr.Number), nil
+}
+
+func integer(name string, expr *ex.Expr) (int64, *ex.Expr) {
+	if expr.Type != ex.Number || expr.Number != math.Trunc(expr.Number) {
+		return 0, ex.NewFatal(name + ": expected integer, given " + given(expr))
+	}
+
+	// conversion of numbers out of range of int64 is implementation-defined
+	if math.Abs(expr.Number) >= math.Exp2(63) {
+		return 0, ex.NewFatal(name + ": integer is out of range, given " + expr.ToString())
+	}
+
+	return int64(expr.Number), nil
+}
+
+func callable(name string, expr *ex.Expr) *ex.Expr {
+	if expr.Type != ex.Function && expr.Type != ex.Closure && expr.Type != ex.Parameter {
+		return ex.NewFatal(name + ": expected function, given " + expr.ToString())
+	}
+
+	return nil
+}
+
+func flatten(expr *ex.Expr, res []*ex.Expr) []*ex.Expr {
+	for expr.Type == ex.Pair {
+		if car := expr.Car(); car.Type == ex.Pair || car.Type == ex.Nil {
+			res = flatten(car, res)
+		} else {
+			res = append(res, car)
+		}
+		expr = expr.Cdr()
+	}
+
+	// improper tail is a leaf
+	if expr.Type != ex.Nil {
+		res = append(res, expr)
+	}
+
+	return res
+}
+
+func listExtreme(name string, args []*ex.Expr, less func(a, b float64) bool) *ex.Expr {
+	if len(args) != 1 {
+		return ex.NewFatal(name + ": must be 1 argument")
+	}
+
+	list, ok := args[0].ToSlice()
+	if !ok || len(list) == 0 {
+		return ex.NewFatal(name + ": expected non-empty list")
+	}
+
+	res := list[0]
+	for _, elem := range list {
+		if elem.Type != ex.Number {
+			return ex.NewFatal(name + ": expected numbers, given " + given(elem))
+		}
+
+		if less(elem.Number, res.Number) {
+			res = elem
+		}
+	}
+
+	return res
+}
+
+func trim(name string, args []*ex.Expr, trimSpace func(string) string, trimCutset func(string, string) string) *ex.Expr {
+	if len(args) != 1 && len(args) != 2 {
+		return ex.NewFatal(name + ": expected 1 or 2 arguments")
+	}
+
+	for _, arg := range args {
+		if arg.Type != ex.Symbol {
+			return ex.NewFatal(name + ": expected symbols, given " + arg.ToString())
+		}
+	}
+
+	if len(args) == 2 {
+		return ex.NewSymbol(trimCutset(args[0].String, args[1].String))
+	}
+
+	return ex.NewSymbol(trimSpace(args[0].String))
+}
+
+// pad pads symbol with pad character or truncates it to width runes. Left padding keeps the rightmost runes
+// when truncating, right padding keeps the leftmost ones
+func pad(name string, args []*ex.Expr, left bool) *ex.Expr {
+	if len(args) != 2 && len(args) != 3 {
+		return ex.NewFatal(name + ": expected 2 or 3 arguments")
+	}
+
+	if args[0].Type != ex.Symbol {
+		return ex.NewFatal(name + ": expected symbol, given " + args[0].ToString())
+	}
+
+	width, fatal := natural(name, args[1])
+	if fatal != nil {
+		return fatal
+	}
+
+	if width > maxSymbolLength {
+		return ex.NewFatal(name + ": width is too large")
+	}
+
+	padChar := ' '
+	if len(args) == 3 {
+		if padChar, fatal = char(name, args[2]); fatal != nil {
+			return fatal
+		}
+	}
+
+	runes := []rune(args[0].String)
+	if len(runes) >= width {
+		if left {
+			return ex.NewSymbol(string(runes[len(runes)-width:]))
+		}
+
+		return ex.NewSymbol(string(runes[:width]))
+	}
+
+	padding := strings.Repeat(string(padChar), width-len(runes))
+	if left {
+		return ex.NewSymbol(padding + string(runes))
+	}
+
+	return ex.NewSymbol(string(runes) + padding)
+}
+
+// char returns character of symbol that consists of one character
+func char(name string, expr *ex.Expr) (rune, *ex.Expr) {
+	if runes := []rune(expr.String); expr.Type == ex.Symbol && len(runes) == 1 {
+		return runes[0], nil
+	}
+
+	return 0, ex.NewFatal(name + ": expected character, given " + expr.ToString())
+}
+
+func charPredicate(name string, args []*ex.Expr, is func(rune) bool) *ex.Expr {
+	if len(args) != 1 {
+		return ex.NewFatal(name + ": must be 1 argument")
+	}
+
+	r, fatal := char(name, args[0])
+	if fatal != nil {
+		return fatal
+	}
+
+	if is(r) {
+		return ex.NewT()
+	}
+
+	return ex.NewNil()
+}
+
+// readChar reads character from input channel, peeked character is returned to the channel. Returns EOF object at
+// the end of input
+func readChar(name string, ir *interpreter, peek bool) *ex.Expr {
+	r, _, err := ir.stdin.ReadRune()
+	if err == io.EOF {
+		return ex.NewEOF()
+	}
+
+	if err != nil {
+		return ex.NewFatal(name + ": " + err.Error())
+	}
+
+	if peek {
+		_ = ir.stdin.UnreadRune()
+	}
+
+	return ex.NewSymbol(string(r))
+}
+
+// readable returns representation of expression that can be read back by parser
+func readable(expr *ex.Expr) string {
+	switch expr.Type {
+	case ex.Symbol:
+		return "|" + strings.NewReplacer("\\", "\\\\", "|", "\\|").Replace(expr.String) + "|"
+	case ex.Pair:
+		list, _ := expr.ToSlice()
+		strs := make([]string, len(list))
+		for i, elem := range list {
+			strs[i] = readable(elem)
+		}
+		return "(" + strings.Join(strs, " ") + ")"
+	case ex.Vector:
+		strs := make([]string, len(expr.Vector))
+		for i, elem := range expr.Vector {
+			strs[i] = readable(elem)
+		}
+		return "#(" + strings.Join(strs, " ") + ")"
+	default:
+		return expr.ToString()
+	}
+}
+
+// copyTree returns copy of tree of pairs. Leaves aren't copied
+func copyTree(expr *ex.Expr) *ex.Expr {
+	if expr.Type != ex.Pair {
+		return expr
+	}
+
+	list, _ := expr.ToSlice()
+	for i, elem := range list {
+		list[i] = copyTree(elem)
+	}
+
+	return ex.NewList(list...)
+}
+
+// lastPair returns last pair of proper non-circular list
+func lastPair(name string, list *ex.Expr) (*ex.Expr, *ex.Expr) {
+	slow, fast := list, list
+	for {
+		if fast.Type != ex.Pair {
+			return nil, ex.NewFatal(name + ": expected proper list")
+		}
+
+		if fast.Cdr().Type == ex.Nil {
+			return fast, nil
+		}
+
+		fast = fast.Cdr()
+		if fast.Type != ex.Pair {
+			return nil, ex.NewFatal(name + ": expected proper list")
+		}
+
+		if fast.Cdr().Type == ex.Nil {
+			return fast, nil
+		}
+
+		fast, slow = fast.Cdr(), slow.Cdr()
+		if fast == slow {
+			return nil, ex.NewFatal(name + ": expected non-circular list")
+		}
+	}
+}
+
+// caseMatch reports whether key is equal to datum or is in numeric range (from to) given by datum
+func caseMatch(key, datum *ex.Expr) bool {
+	if key.Type == ex.Number && datum.Type == ex.Pair && datum.Length() == 2 &&
+		datum.Index(0).Type == ex.Number && datum.Index(1).Type == ex.Number {
+		return datum.Index(0).Number <= key.Number && key.Number <= datum.Index(1).Number
+	}
+
+	return key.Equal(datum)
 }
 
-var functions = map[string]Func{
+func stream(name string, expr *ex.Expr) *ex.Expr {
+	if expr.Type != ex.Pair || expr.Length() != 2 || expr.Index(1).Type != ex.Promise {
+		return ex.NewFatal(name + ": expected stream, given " + expr.ToString())
+	}
+
+	return nil
+}
+
+// vectorRange returns range of vector given by optional start and end indexes (whole vector by default)
+func vectorRange(name string, vec *ex.Expr, bounds []*ex.Expr) (int, int, *ex.Expr) {
+	start, end := 0, len(vec.Vector)
+	for i, bound := range bounds {
+		n, fatal := natural(name, bound)
+		if fatal != nil {
+			return 0, 0, fatal
+		}
+
+		if i == 0 {
+			start = n
+		} else {
+			end = n
+		}
+	}
+
+	if start > end || end > len(vec.Vector) {
+		return 0, 0, ex.NewFatal(fmt.Sprintf("%s: incorrect range from %d to %d of vector of length %d", name, start, end, len(vec.Vector)))
+	}
+
+	return start, end, nil
+}
+
+// calls returns calls of function with quoted elements of sequences with equal indexes. Elements of longer sequences
+// after the end of the shortest one are ignored
+func calls(f *ex.Expr, seqs [][]*ex.Expr) []*ex.Expr {
+	length := -1
+	for _, seq := range seqs {
+		if length == -1 || len(seq) < length {
+			length = len(seq)
+		}
+	}
+
+	res := make([]*ex.Expr, length)
+	for i := range res {
+		call := []*ex.Expr{f}
+		for _, seq := range seqs {
+			call = append(call, quote(seq[i]))
+		}
+
+		res[i] = ex.NewList(call...)
+	}
+
+	return res
+}
+
+// listCode returns code that calculates expressions in turn and returns list of results
+func listCode(exprs []*ex.Expr) *ex.Expr {
+	code := ex.NewNil()
+	for i := len(exprs) - 1; i >= 0; i-- {
+		code = ex.NewList(ex.NewFunction("cons"), exprs[i], code)
+	}
+
+	return code
+}
+
+// mappedVectors checks arguments of function that maps vectors (function and at least one vector) and returns elements
+// of vectors
+func mappedVectors(name string, args []*ex.Expr) ([][]*ex.Expr, *ex.Expr) {
+	if len(args) < 2 {
+		return nil, ex.NewFatal(name + ": must be at less 2 arguments")
+	}
+
+	if fatal := callable(name, args[0]); fatal != nil {
+		return nil, fatal
+	}
+
+	vectors := make([][]*ex.Expr, len(args)-1)
+	for i, arg := range args[1:] {
+		if arg.Type != ex.Vector {
+			return nil, ex.NewFatal(name + ": expected vectors, given " + arg.ToString())
+		}
+
+		vectors[i] = arg.Vector
+	}
+
+	return vectors, nil
+}
+
+// divisionArgs checks arguments of integer division and returns dividend and divisor
+func divisionArgs(name string, args []*ex.Expr) (float64, float64, *ex.Expr) {
+	if len(args) != 2 {
+		return 0, 0, ex.NewFatal(name + ": must be 2 arguments")
+	}
+
+	for _, arg := range args {
+		if arg.Type != ex.Number {
+			return 0, 0, ex.NewFatal(name + ": expected numbers, given " + given(arg))
+		}
+	}
+
+	if args[1].Number == 0 {
+		return 0, 0, ex.NewFatal(name + ": zero division")
+	}
+
+	return args[0].Number, args[1].Number, nil
+}
+
+// bitwise folds integer values of arguments by operation starting with identity value
+func bitwise(name string, args []*ex.Expr, identity int64, op func(a, b int64) int64) *ex.Expr {
+	res := identity
+	for _, arg := range args {
+		n, fatal := integer(name, arg)
+		if fatal != nil {
+			return fatal
+		}
+
+		res = op(res, n)
+	}
+
+	return ex.NewNumber(float64(res))
+}
+
+// numbers checks that arguments are numbers and returns their values
+func numbers(name string, args []*ex.Expr) ([]float64, *ex.Expr) {
+	res := make([]float64, len(args))
+	for i, arg := range args {
+		if arg.Type != ex.Number {
+			return nil, ex.NewFatal(name + ": expected numbers, given " + given(arg))
+		}
+
+		res[i] = arg.Number
+	}
+
+	return res, nil
+}
+
+// mathFunc applies function of one number to argument. Argument out of domain of function is an error
+func mathFunc(name string, args []*ex.Expr, f func(float64) float64, domain func(float64) bool) *ex.Expr {
+	if len(args) != 1 {
+		return ex.NewFatal(name + ": must be 1 argument")
+	}
+
+	x, fatal := numbers(name, args)
+	if fatal != nil {
+		return fatal
+	}
+
+	if domain != nil && !domain(x[0]) {
+		return ex.NewFatal(name + ": argument is out of domain: " + args[0].ToString())
+	}
+
+	return ex.NewNumber(f(x[0]))
+}
+
+func bindingForm(name string, form *ex.Expr) (*ex.Expr, *ex.Expr, *ex.Expr) {
+	if form.Type != ex.Pair || form.Length() != 2 || form.Car().Type != ex.Symbol {
+		return nil, nil, ex.NewFatal(name + ": binding must be a list of symbol and expression")
+	}
+
+	return form.Car(), form.Index(1), nil
+}
+
+// letrec returns call of closure without arguments which body defines bindings and calculates body of letrec. Bindings
+// of letrec* are defined in order, letrec binds all names to nil before any expression is calculated and defines them
+// after all expressions are calculated (by %letrec-define). The call is calculated in place of letrec, so calls in tail
+// position of body don't grow the stack
+func letrec(name string, ir *interpreter, args []*ex.Expr) *ex.Expr {
+	if len(args) < 2 {
+		return ex.NewFatal(name + ": must be at less 2 arguments")
+	}
+
+	bindings, ok := args[0].ToSlice()
+	if !ok {
+		return ex.NewFatal(name + ": first argument must be a list of bindings")
+	}
+
+	var body, syms, exprs []*ex.Expr
+	for _, binding := range bindings {
+		sym, expr, fatal := bindingForm(name, binding)
+		if fatal != nil {
+			return fatal
+		}
+
+		if name == "letrec*" {
+			body = append(body, ex.NewList(ex.NewFunction("define"), sym, expr))
+			continue
+		}
+
+		body = append(body, ex.NewList(ex.NewFunction("define"), sym, ex.NewNil()))
+		syms = append(syms, sym)
+		exprs = append(exprs, expr)
+	}
+
+	if len(syms) > 0 {
+		body = append(body, ex.NewFunction("%letrec-define").Cons(quote(ex.NewList(syms...)).Cons(ex.NewList(exprs...))))
+	}
+
+	body = append(body, args[1:]...)
+
+	return ex.NewClosure(ex.NewNil(), body, ir.varsEnvironment).ToList()
+}
+
+// matchPattern matches value with pattern of match and appends definitions of pattern's variables to defs. Symbol '_'
+// matches any value, other symbols are variables, quoted expressions and the rest atoms match equal values. Lists match
+// lists of the same length, tail of list is matched by the pattern after symbol '.' (e.g. (a b . rest))
+func matchPattern(pattern, value *ex.Expr, defs []*ex.Expr) ([]*ex.Expr, bool) {
+	switch pattern.Type {
+	case ex.Symbol:
+		if pattern.String == "_" {
+			return defs, true
+		}
+
+		return append(defs, ex.NewList(ex.NewFunction("define"), pattern, quote(value))), true
+
+	case ex.Pair:
+		head := pattern.Car()
+		if (head.Type == ex.Symbol || head.Type == ex.Function) && head.String == "quote" && pattern.Length() == 2 {
+			return defs, pattern.Index(1).Equal(value)
+		}
+
+		for pattern.Type == ex.Pair {
+			if dot := pattern.Car(); dot.Type == ex.Symbol && dot.String == "." && pattern.Length() == 2 {
+				return matchPattern(pattern.Index(1), value, defs)
+			}
+
+			if value.Type != ex.Pair {
+				return nil, false
+			}
+
+			var ok bool
+			if defs, ok = matchPattern(pattern.Car(), value.Car(), defs); !ok {
+				return nil, false
+			}
+
+			pattern, value = pattern.Cdr(), value.Cdr()
+		}
+
+		return matchPattern(pattern, value, defs)
+
+	default:
+		return defs, pattern.Equal(value)
+	}
+}
+
+// thread returns code of threading macros: the first argument is passed to the first form, its result to the next one
+// and so on. Form that isn't a list is called with the value, list is called with the value inserted as the first or
+// the last argument
+func thread(name string, args []*ex.Expr, last bool) *ex.Expr {
+	if len(args) < 1 {
+		return ex.NewFatal(name + ": must be at less 1 argument")
+	}
+
+	res := args[0]
+	for _, form := range args[1:] {
+		if form.Type != ex.Pair {
+			res = ex.NewList(form, res)
+			continue
+		}
+
+		call, ok := form.ToSlice()
+		if !ok {
+			return ex.NewFatal(name + ": expected list, given " + form.ToString())
+		}
+
+		if last {
+			res = ex.NewList(append(call, res)...)
+		} else {
+			res = ex.NewList(append([]*ex.Expr{call[0], res}, call[1:]...)...)
+		}
+	}
+
+	return begin(res)
+}
+
+// prefixed reports whether expression is a list of symbol and one element, like (unquote x) read from ,x
+func prefixed(expr *ex.Expr, symbol string) bool {
+	return expr.Type == ex.Pair && expr.Length() == 2 && expr.Car().Type == ex.Symbol && expr.Car().String == symbol
+}
+
+// quasiquote returns code that builds template: unquoted expressions are calculated, lists calculated by
+// unquote-splicing are spliced, the rest parts are quoted. Depth is a number of quasiquotes nested in the outer one,
+// only unquotes of depth 0 are calculated, nested quasiquotes and unquotes are built as lists
+func quasiquote(template *ex.Expr, depth int) (*ex.Expr, *ex.Expr) {
+	elems, ok := template.ToSlice()
+	if template.Type != ex.Pair || !ok {
+		if template.Type == ex.Vector {
+			list, fatal := quasiquoteList(template.Vector, depth)
+			if fatal != nil {
+				return nil, fatal
+			}
+
+			return ex.NewList(ex.NewFunction("list->vector"), list), nil
+		}
+
+		return quote(template), nil
+	}
+
+	switch {
+	case prefixed(template, "unquote"):
+		if depth == 0 {
+			return template.Index(1), nil
+		}
+
+		return quasiquoteList(elems, depth-1)
+
+	case prefixed(template, "unquote-splicing"):
+		if depth == 0 {
+			return nil, ex.NewFatal("unquote-splicing: expected in list")
+		}
+
+		return quasiquoteList(elems, depth-1)
+
+	case prefixed(template, "quasiquote"):
+		return quasiquoteList(elems, depth+1)
+	}
+
+	return quasiquoteList(elems, depth)
+}
+
+// quasiquoteList returns code that builds list of templates
+func quasiquoteList(elems []*ex.Expr, depth int) (*ex.Expr, *ex.Expr) {
+	res := ex.NewNil()
+	for i := len(elems) - 1; i >= 0; i-- {
+		if depth == 0 && prefixed(elems[i], "unquote-splicing") {
+			res = ex.NewList(ex.NewFunction("%splice"), elems[i].Index(1), res)
+			continue
+		}
+
+		elem, fatal := quasiquote(elems[i], depth)
+		if fatal != nil {
+			return nil, fatal
+		}
+
+		res = ex.NewList(ex.NewFunction("cons"), elem, res)
+	}
+
+	return res, nil
+}
+
+// recordField returns field of define-record-type: list of field's name, accessor and optional modifier
+func recordField(spec *ex.Expr) ([]*ex.Expr, *ex.Expr) {
+	field, ok := spec.ToSlice()
+	if !ok || len(field) != 2 && len(field) != 3 {
+		return nil, ex.NewFatal("define-record-type: field must be a list of name, accessor and optional modifier")
+	}
+
+	for _, sym := range field {
+		if sym.Type != ex.Symbol {
+			return nil, ex.NewFatal("define-record-type: expected symbol, given " + sym.ToString())
+		}
+	}
+
+	return field, nil
+}
+
+// continuationTag is a prefix of errors thrown by continuations. The errors aren't caught by default handlers of catch
+const continuationTag = "%continuation-"
+
+// callCC returns code that calls function with escape continuation. Continuation throws error with its own tag that is
+// caught by catch around the call, it can be called only until the call ends
+func callCC(name string, ir *interpreter, args []*ex.Expr) *ex.Expr {
+	if len(args) != 1 {
+		return ex.NewFatal(name + ": must be 1 argument")
+	}
+
+	if fatal := callable(name, args[0]); fatal != nil {
+		return fatal
+	}
+
+	ir.continuationsNum++
+	tag := ex.NewSymbol(continuationTag + strconv.Itoa(ir.continuationsNum) + ":")
+	ir.continuations[tag.String] = struct{}{}
+
+	k := ex.NewClosure(ex.NewSymbol("value").ToList(),
+		[]*ex.Expr{ex.NewList(ex.NewFunction("%continue"), quote(tag), ex.NewSymbol("value"))}, ir.varsEnvironment)
+	end := ex.NewClosure(ex.NewSymbol("res").ToList(),
+		[]*ex.Expr{ex.NewList(ex.NewFunction("%end-continuation"), quote(tag)), ex.NewSymbol("res")}, ir.varsEnvironment)
+
+	return begin(ex.NewList(ex.NewFunction("catch"),
+		ex.NewList(ex.NewFunction("%unwind"), end, ex.NewList(args[0], k)), tag.ToList()))
+}
+
+var functions = map[string]Func{
+
+	"eval": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFunction("begin").Cons(ex.NewFatal("quote: must be 1 argument").ToList())
+			}
+
+			return ex.NewFunction("begin").Cons(args[0].ToList())
+		},
+		Eval: true,
+	},
+
+	"load-string": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("load-string: must be 1 argument")
+			}
+
+			if args[0].Type != ex.Symbol {
+				return ex.NewFatal("load-string: expected symbol, given " + args[0].ToString())
+			}
+
+			exprs, err := parser.NewParser(args[0].String).Parse()
+			if err != nil {
+				return ex.NewFatal("load-string: " + err.Error())
+			}
+
+			return ex.NewFunction("begin").Cons(exprs)
+		},
+		Eval: true,
+	},
+
+	// define-record-type defines closures which call functions of records with prototype - record without fields
+	// that carries the type
+	"define-record-type": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) < 3 {
+				return ex.NewFatal("define-record-type: must be at less 3 arguments")
+			}
+
+			constructor, ok := args[1].ToSlice()
+			if args[0].Type != ex.Symbol || !ok || len(constructor) == 0 || args[2].Type != ex.Symbol {
+				return ex.NewFatal("define-record-type: expected name, constructor list and predicate")
+			}
+
+			for _, arg := range constructor {
+				if arg.Type != ex.Symbol {
+					return ex.NewFatal("define-record-type: expected symbol, given " + arg.ToString())
+				}
+			}
+
+			recordType := &ex.RecordType{Name: args[0].String}
+			indexes := map[string]int{}
+			var fields [][]*ex.Expr
+			for _, spec := range args[3:] {
+				field, fatal := recordField(spec)
+				if fatal != nil {
+					return fatal
+				}
+
+				if _, ok := indexes[field[0].String]; ok {
+					return ex.NewFatal("define-record-type: field '" + field[0].String + "' is defined twice")
+				}
+
+				indexes[field[0].String] = len(recordType.Fields)
+				recordType.Fields = append(recordType.Fields, field[0].String)
+				fields = append(fields, field)
+			}
+
+			// fields which aren't arguments of constructor are nil
+			values := make([]*ex.Expr, len(recordType.Fields))
+			for i := range values {
+				values[i] = ex.NewNil()
+			}
+
+			for _, arg := range constructor[1:] {
+				i, ok := indexes[arg.String]
+				if !ok {
+					return ex.NewFatal("define-record-type: field '" + arg.String + "' of constructor is not defined")
+				}
+
+				values[i] = arg
+			}
+
+			proto := ex.NewRecord(recordType, nil)
+			defineClosure := func(name *ex.Expr, params *ex.Expr, body *ex.Expr) *ex.Expr {
+				return ex.NewList(ex.NewFunction("define"), name, ex.NewClosure(params, []*ex.Expr{body}, ir.varsEnvironment))
+			}
+
+			defs := []*ex.Expr{
+				defineClosure(constructor[0], ex.NewList(constructor[1:]...),
+					ex.NewFunction("%make-record").Cons(proto.Cons(ex.NewList(values...)))),
+				defineClosure(args[2], ex.NewList(ex.NewSymbol("x")),
+					ex.NewList(ex.NewFunction("%record?"), proto, ex.NewSymbol("x"))),
+			}
+
+			for i, field := range fields {
+				defs = append(defs, defineClosure(field[1], ex.NewList(ex.NewSymbol("r")), ex.NewList(ex.NewFunction("%record-ref"),
+					proto, ex.NewNumber(float64(i)), ex.NewSymbol("r"), quote(field[1]))))
+
+				if len(field) == 3 {
+					defs = append(defs, defineClosure(field[2], ex.NewList(ex.NewSymbol("r"), ex.NewSymbol("v")),
+						ex.NewList(ex.NewFunction("%record-set!"), proto, ex.NewNumber(float64(i)), ex.NewSymbol("r"),
+							ex.NewSymbol("v"), quote(field[2]))))
+				}
+			}
+
+			return begin(append(defs, quote(args[0]))...)
+		},
+		Mod: &Mod{
+			Type: ModExec,
+			Exec: map[int]struct{}{},
+		},
+		Eval: true,
+	},
+
+	"%make-record": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			return ex.NewRecord(args[0].RecordType, args[1:])
+		},
+	},
+
+	"%record?": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if args[1].Type == ex.Record && args[1].RecordType == args[0].RecordType {
+				return ex.NewT()
+			}
+
+			return ex.NewNil()
+		},
+	},
+
+	// (%record-ref prototype index record name) returns field of record, name of accessor is used in errors
+	"%record-ref": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if args[2].Type != ex.Record || args[2].RecordType != args[0].RecordType {
+				return ex.NewFatal(args[3].String + ": expected " + args[0].RecordType.Name + ", given " + args[2].ToString())
+			}
+
+			return args[2].Vector[int(args[1].Number)]
+		},
+	},
+
+	// (%record-set! prototype index record value name) changes field of record, name of modifier is used in errors
+	"%record-set!": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if args[2].Type != ex.Record || args[2].RecordType != args[0].RecordType {
+				return ex.NewFatal(args[4].String + ": expected " + args[0].RecordType.Name + ", given " + args[2].ToString())
+			}
+
+			args[2].Vector[int(args[1].Number)] = args[3]
+			return args[3]
+		},
+	},
+
+	"record?": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("record?: must be 1 argument")
+			}
+
+			if args[0].Type == ex.Record {
+				return ex.NewT()
+			}
+
+			return ex.NewNil()
+		},
+	},
+
+	"record-type-name": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("record-type-name: must be 1 argument")
+			}
+
+			if args[0].Type != ex.Record {
+				return ex.NewFatal("record-type-name: expected record, given " + args[0].ToString())
+			}
+
+			return ex.NewSymbol(args[0].RecordType.Name)
+		},
+	},
+
+	// record-fields returns association list of names and values of fields in order of definition
+	"record-fields": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("record-fields: must be 1 argument")
+			}
+
+			if args[0].Type != ex.Record {
+				return ex.NewFatal("record-fields: expected record, given " + args[0].ToString())
+			}
+
+			fields := make([]*ex.Expr, len(args[0].Vector))
+			for i, value := range args[0].Vector {
+				fields[i] = ex.NewList(ex.NewSymbol(args[0].RecordType.Fields[i]), value)
+			}
+
+			return ex.NewList(fields...)
+		},
+	},
+
+	"quasiquote": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("quasiquote: must be 1 argument")
+			}
+
+			code, fatal := quasiquote(args[0], 0)
+			if fatal != nil {
+				return fatal
+			}
+
+			return begin(code)
+		},
+		Mod: &Mod{
+			Type: ModExec,
+			Exec: map[int]struct{}{},
+		},
+		Eval: true,
+	},
+
+	// (%splice list rest) returns copy of list followed by rest
+	"%splice": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			elems, ok := args[0].ToSlice()
+			if !ok {
+				return ex.NewFatal("unquote-splicing: expected list, given " + args[0].ToString())
+			}
+
+			res := args[1]
+			for i := len(elems) - 1; i >= 0; i-- {
+				res = elems[i].Cons(res)
+			}
+
+			return res
+		},
+	},
+
+	"quote": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("quote: must be 1 argument")
+			}
+
+			return args[0]
+		},
+		Mod: &Mod{
+			Type: ModExec,
+			Exec: map[int]struct{}{},
+		},
+	},
+
+	"catch": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) == 0 {
+				return ex.NewFatal("catch: must be at least one argument")
+			}
+
+			if args[0].Type == ex.Fatal {
+				panic("catch: it shouldn't have happened")
+			}
+
+			return args[0]
+		},
+		Mod: &Mod{
+			Type: ModTry,
+		},
+	},
+
+	"throw": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 && len(args) != 2 {
+				return ex.NewFatal("throw: must be one or two arguments")
+			}
+
+			if args[0].Type != ex.Symbol {
+				return ex.NewFatal("throw: first argument must be a symbol")
+			}
+
+			if len(args) == 2 {
+				return ex.NewFatal(args[0].String, args[1])
+			}
+
+			return ex.NewFatal(args[0].String)
+		},
+	},
+
+	"assert": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 && len(args) != 2 {
+				return ex.NewFatal("assert: must be one or two arguments")
+			}
+
+			tag := quote(ex.NewSymbol("assert: failed " + args[0].ToString()))
+			if len(args) == 2 {
+				tag = ex.NewList(ex.NewFunction("+"), quote(ex.NewSymbol("assert: ")), args[1])
+			}
+
+			return begin(ex.NewList(ex.NewFunction("if"), args[0], ex.NewT(), ex.NewList(ex.NewFunction("throw"), tag)))
+		},
+		Mod: &Mod{
+			Type: ModExec,
+			Exec: map[int]struct{}{},
+		},
+		Eval: true,
+	},
+
+	"assert-equal": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 2 {
+				return ex.NewFatal("assert-equal: must be 2 arguments")
+			}
+
+			if !args[0].Equal(args[1]) {
+				return ex.NewFatal("assert-equal: expected " + args[1].ToString() + ", given " + args[0].ToString())
+			}
+
+			return ex.NewT()
+		},
+	},
+
+	"deftest": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) < 2 {
+				return ex.NewFatal("deftest: must be at less 2 arguments")
+			}
+
+			if args[0].Type != ex.Symbol {
+				return ex.NewFatal("deftest: expected symbol, given " + args[0].ToString())
+			}
+
+			test := registeredTest{name: args[0].String, thunk: ex.NewClosure(ex.NewNil(), args[1:], ir.varsEnvironment)}
+			test.thunk.String = test.name
+
+			// redefined test keeps its place in order of tests
+			for i := range ir.tests {
+				if ir.tests[i].name == test.name {
+					ir.tests[i] = test
+					return args[0]
+				}
+			}
+
+			ir.tests = append(ir.tests, test)
+			return args[0]
+		},
+		Mod: &Mod{
+			Type: ModExec,
+			Exec: map[int]struct{}{},
+		},
+	},
+
+	// run-tests calculates every test inside catch, so failed test doesn't stop the rest ones
+	"run-tests": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 0 {
+				return ex.NewFatal("run-tests: expected zero arguments")
+			}
+
+			summary := []*ex.Expr{ex.NewFunction("%tests-summary")}
+			for _, test := range ir.tests {
+				run := ex.NewList(ex.NewFunction("catch"), begin(test.thunk.ToList(), ex.NewNil()),
+					ex.NewList(ex.NewSymbol("default"), ex.NewSymbol("error_description")))
+				summary = append(summary, ex.NewList(ex.NewFunction("%test-result"), quote(ex.NewSymbol(test.name)), run))
+			}
+
+			return ex.NewList(summary...)
+		},
+		Eval: true,
+	},
+
+	// (%test-result name error) writes result of test and returns number of its failures: 0 if error is nil or 1
+	"%test-result": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			res, failures := "PASS "+args[0].String, 0
+			if !args[1].IsNil() {
+				res, failures = "FAIL "+args[0].String+": "+args[1].ToString(), 1
+			}
+
+			if _, err := fmt.Fprintln(ir.stdout, res); err != nil {
+				return ex.NewFatal(err.Error())
+			}
+
+			return ex.NewNumber(float64(failures))
+		},
+	},
+
+	"%tests-summary": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			failures := 0
+			for _, arg := range args {
+				failures += int(arg.Number)
+			}
+
+			_, err := fmt.Fprintf(ir.stdout, "%d tests, %d passed, %d failed\n", len(args), len(args)-failures, failures)
+			if err != nil {
+				return ex.NewFatal(err.Error())
+			}
+
+			return ex.NewNumber(float64(failures))
+		},
+	},
+
+	"dynamic-wind": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 3 {
+				return ex.NewFatal("dynamic-wind: must be 3 arguments")
+			}
+
+			for _, arg := range args {
+				if fatal := callable("dynamic-wind", arg); fatal != nil {
+					return fatal
+				}
+			}
+
+			after := ex.NewClosure(ex.NewSymbol("res").ToList(), []*ex.Expr{args[2].ToList(), ex.NewSymbol("res")}, ir.varsEnvironment)
+
+			return begin(args[0].ToList(), ex.NewList(ex.NewFunction("%unwind"), after, args[1].ToList()))
+		},
+		Eval: true,
+	},
+
+	// (%unwind cleanup value) calls cleanup with value. If an error falls through value calculation,
+	// cleanup is called with nil before the error falls further (see fatalFall)
+	"%unwind": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 2 {
+				return ex.NewFatal("%unwind: must be 2 arguments")
+			}
+
+			return ex.NewList(args[0], quote(args[1]))
+		},
+		Eval: true,
+	},
+
+	"call-with-current-continuation": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			return callCC("call-with-current-continuation", ir, args)
+		},
+		Eval: true,
+	},
+
+	"call/cc": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			return callCC("call/cc", ir, args)
+		},
+		Eval: true,
+	},
+
+	"%continue": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if _, ok := ir.continuations[args[0].String]; !ok {
+				return ex.NewFatal("continuation: call/cc has already returned, continuations can only escape")
+			}
+
+			return ex.NewFatal(args[0].String, args[1])
+		},
+	},
+
+	"%end-continuation": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			delete(ir.continuations, args[0].String)
+			return ex.NewNil()
+		},
+	},
+
+	"->": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			return thread("->", args, false)
+		},
+		Mod: &Mod{
+			Type: ModExec,
+			Exec: map[int]struct{}{},
+		},
+		Eval: true,
+	},
+
+	"->>": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			return thread("->>", args, true)
+		},
+		Mod: &Mod{
+			Type: ModExec,
+			Exec: map[int]struct{}{},
+		},
+		Eval: true,
+	},
+
+	"case-lambda": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) < 1 {
+				return ex.NewFatal("case-lambda: must be at less 1 argument")
+			}
+
+			// closures of clauses are kept in body of variadic closure that calls one of them by number of arguments
+			dispatch := []*ex.Expr{ex.NewFunction("%case-lambda"), ex.NewSymbol("args")}
+			for _, clause := range args {
+				if clause.Type != ex.Pair || clause.Length() < 2 {
+					return ex.NewFatal("case-lambda: clause must be a list of formals and expressions")
+				}
+
+				body, _ := clause.Cdr().ToSlice()
+				closure := ex.NewClosure(clause.Car(), body, ir.varsEnvironment)
+				if closure.Type == ex.Fatal {
+					return closure
+				}
+
+				dispatch = append(dispatch, closure)
+			}
+
+			return ex.NewClosure(ex.NewSymbol("args"), []*ex.Expr{ex.NewList(dispatch...)}, ir.varsEnvironment)
+		},
+		Mod: &Mod{
+			Type: ModExec,
+			Exec: map[int]struct{}{},
+		},
+	},
+
+	// (%case-lambda args closure...) calls the first closure that accepts arguments
+	"%case-lambda": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			callArgs, _ := args[0].ToSlice()
+			for _, closure := range args[1:] {
+				if !closure.AcceptsArgs(len(callArgs)) {
+					continue
+				}
+
+				call := []*ex.Expr{closure}
+				for _, arg := range callArgs {
+					call = append(call, quote(arg))
+				}
+
+				return ex.NewList(call...)
+			}
+
+			return ex.NewFatal("case-lambda: no clause accepts " + strconv.Itoa(len(callArgs)) + " args")
+		},
+		Eval: true,
+	},
+
+	"match": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) < 2 {
+				return ex.NewFatal("match: must be at less 2 arguments")
+			}
+
+			for _, clause := range args[1:] {
+				if clause.Type != ex.Pair || clause.Length() < 2 {
+					return ex.NewFatal("match: clause must be a list of pattern and expressions")
+				}
+			}
+
+			// body of the first matched clause is calculated in a new scope with variables of the pattern
+			for _, clause := range args[1:] {
+				defs, ok := matchPattern(clause.Car(), args[0], nil)
+				if !ok {
+					continue
+				}
+
+				body, _ := clause.Cdr().ToSlice()
+				return ex.NewClosure(ex.NewNil(), append(defs, body...), ir.varsEnvironment).ToList()
+			}
+
+			return ex.NewFatal("match: no clause matches " + args[0].ToString())
+		},
+		Mod: &Mod{
+			Type: ModExec,
+			Exec: map[int]struct{}{1: {}},
+		},
+		Eval: true,
+	},
+
+	"generator": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) < 1 {
+				return ex.NewFatal("generator: must be at less 1 argument")
+			}
+
+			return ex.NewGenerator(ex.NewClosure(ex.NewNil(), args, ir.varsEnvironment))
+		},
+		Mod: &Mod{
+			Type: ModExec,
+			Exec: map[int]struct{}{},
+		},
+	},
+
+	// generator-next resumes calculation of generator's closure from the last yield or starts it. Stacks above the call
+	// of generator-next belong to the generator, yield saves them and returns to the caller
+	"generator-next": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("generator-next: must be 1 argument")
+			}
+
+			g := args[0]
+			if g.Type != ex.Generator {
+				return ex.NewFatal("generator-next: expected generator, given " + g.ToString())
+			}
+
+			if g.Res != nil {
+				return begin(g.Res)
+			}
+
+			for _, run := range ir.generators {
+				if run.generator == g {
+					return ex.NewFatal("generator-next: generator is already running")
+				}
+			}
+
+			ir.generators = append(ir.generators, generatorRun{
+				generator: g,
+				calls:     len(ir.callStack),
+				data:      len(ir.dataStack),
+				vars:      ir.varsEnvironment,
+			})
+
+			suspended, ok := ir.suspended[g]
+			if !ok {
+				return ex.NewList(ex.NewFunction("%unwind"), ex.NewFunction("%generator-stop"), g.GeneratorThunk().ToList())
+			}
+
+			delete(ir.suspended, g)
+			ir.callStack = append(ir.callStack, suspended.calls...)
+			ir.dataStack = append(ir.dataStack, suspended.data...)
+			ir.varsEnvironment = suspended.vars
+
+			// the suspended call of yield returns nil
+			return begin()
+		},
+		Eval: true,
+	},
+
+	// %generator-stop marks generator as exhausted when its closure returns or falls with an error
+	"%generator-stop": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			run := ir.generators[len(ir.generators)-1]
+			ir.generators = ir.generators[:len(ir.generators)-1]
+			ir.varsEnvironment = run.vars
+
+			run.generator.Res = ex.NewEOF()
+			return run.generator.Res
+		},
+	},
+
+	"yield": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("yield: must be 1 argument")
+			}
+
+			if len(ir.generators) == 0 {
+				return ex.NewFatal("yield: called outside of generator")
+			}
+
+			run := ir.generators[len(ir.generators)-1]
+			ir.generators = ir.generators[:len(ir.generators)-1]
+
+			ir.suspended[run.generator] = suspendedGenerator{
+				calls: append([]call{}, ir.callStack[run.calls:]...),
+				data:  append([]*ex.Expr{}, ir.dataStack[run.data:]...),
+				vars:  ir.varsEnvironment,
+			}
+
+			ir.callStack = ir.callStack[:run.calls]
+			ir.dataStack = ir.dataStack[:run.data]
+			ir.varsEnvironment = run.vars
+
+			return args[0]
+		},
+	},
+
+	"make-parameter": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("make-parameter: must be 1 argument")
+			}
+
+			return ex.NewParameter(args[0])
+		},
+	},
+
+	"parameterize": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) < 2 {
+				return ex.NewFatal("parameterize: must be at less 2 arguments")
+			}
+
+			bindings, ok := args[0].ToSlice()
+			if !ok {
+				return ex.NewFatal("parameterize: first argument must be a list of bindings")
+			}
+
+			set := []*ex.Expr{ex.NewFunction("%parameterize")}
+			for _, binding := range bindings {
+				if binding.Type != ex.Pair || binding.Length() != 2 {
+					return ex.NewFatal("parameterize: binding must be a list of parameter and expression")
+				}
+
+				set = append(set, binding.Car(), binding.Index(1))
+			}
+
+			body := ex.NewClosure(ex.NewNil(), args[1:], ir.varsEnvironment).ToList()
+
+			return begin(ex.NewList(set...), ex.NewList(ex.NewFunction("%unwind"), ex.NewFunction("%unparameterize"), body))
+		},
+		Mod: &Mod{
+			Type: ModExec,
+			Exec: map[int]struct{}{},
+		},
+		Eval: true,
+	},
+
+	// (%parameterize param value ...) saves values of parameters and changes them, saved values are restored by
+	// %unparameterize
+	"%parameterize": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			saved := make([]parameterization, 0, len(args)/2)
+			for i := 0; i < len(args); i += 2 {
+				if args[i].Type != ex.Parameter {
+					return ex.NewFatal("parameterize: expected parameter, given " + args[i].ToString())
+				}
+			}
+
+			for i := 0; i < len(args); i += 2 {
+				saved = append(saved, parameterization{param: args[i], value: args[i].ParameterValue()})
+				args[i].SetParameterValue(args[i+1])
+			}
+
+			ir.parameterizations = append(ir.parameterizations, saved)
+			return ex.NewNil()
+		},
+	},
+
+	"%unparameterize": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			last := len(ir.parameterizations) - 1
+			saved := ir.parameterizations[last]
+			ir.parameterizations = ir.parameterizations[:last]
+
+			// parameter could be bound twice by one parameterize, so values are restored in reverse order
+			for i := len(saved) - 1; i >= 0; i-- {
+				saved[i].param.SetParameterValue(saved[i].value)
+			}
+
+			return args[0]
+		},
+	},
+
+	"car": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("car: must be 1 argument")
+			}
+
+			return args[0].Car()
+		},
+	},
+
+	"cdr": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("cdr: must be 1 argument")
+			}
+
+			return args[0].Cdr()
+		},
+	},
+
+	"cons": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 2 {
+				return ex.NewFatal("cons: must be 2 arguments")
+			}
+
+			return args[0].Cons(args[1])
+		},
+	},
+
+	"set-car!": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 2 {
+				return ex.NewFatal("set-car!: must be 2 arguments")
+			}
+
+			return args[0].SetCar(args[1])
+		},
+	},
+
+	"copy-tree": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("copy-tree: must be 1 argument")
+			}
+
+			return copyTree(args[0])
+		},
+	},
+
+	"define": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 2 {
+				return ex.NewFatal("define: must be 2 arguments")
+			}
+
+			if args[0].Type != ex.Symbol {
+				return ex.NewFatal("define: first argument is not a symbol")
+			}
+
+			// closure is named by the first definition, the name is shown in backtraces of errors
+			if args[1].Type == ex.Closure && args[1].String == "" {
+				args[1].String = args[0].String
+			}
+
+			ir.bind(args[0].String, args[1])
+			return args[1]
+		},
+		Mod: &Mod{
+			Type: ModExec,
+			Exec: map[int]struct{}{2: {}},
+		},
+	},
+
+	"defmacro": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) < 3 {
+				return ex.NewFatal("defmacro: must be at less 3 arguments")
+			}
+
+			if args[0].Type != ex.Symbol {
+				return ex.NewFatal("defmacro: second argument is not a symbol")
+			}
+
+			macro := ex.NewMacro(args[1], args[2:], ir.varsEnvironment)
+			ir.bind(args[0].String, macro)
+
+			return macro
+		},
+		Mod: &Mod{
+			Type: ModExec,
+			Exec: map[int]struct{}{},
+		},
+	},
+
+	"set!": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 2 {
+				return ex.NewFatal("set!: must be 2 arguments")
+			}
+
+			if args[0].Type != ex.Symbol {
+				return ex.NewFatal("set!: second argument is not symbol")
+			}
+
+			if scope := ir.findScope(args[0].String); scope != nil {
+				scope.Set(args[0].String, args[1])
+				return args[1]
+			}
+
+			return ex.NewFatal("set!: symbol '" + args[0].String + "' is not defined")
+		},
+		Mod: &Mod{
+			Type: ModExec,
+			Exec: map[int]struct{}{2: {}},
+		},
+	},
+
+	"unset!": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("unset!: must be 1 argument")
+			}
+
+			if args[0].Type != ex.Symbol {
+				return ex.NewFatal("unset!: argument is not symbol")
+			}
+
+			scope := ir.findScope(args[0].String)
+			if scope == nil {
+				return ex.NewFatal("unset!: symbol '" + args[0].String + "' is not defined")
+			}
+
+			res, _ := scope.Get(args[0].String)
+			scope.Delete(args[0].String)
+
+			return res
+		},
+		Mod: &Mod{
+			Type: ModExec,
+			Exec: map[int]struct{}{},
+		},
+	},
+
+	"bound?": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("bound?: must be 1 argument")
+			}
+
+			if args[0].Type != ex.Symbol {
+				return ex.NewFatal("bound?: argument is not a symbol")
+			}
+
+			if ir.findScope(args[0].String) != nil {
+				return ex.NewT()
+			}
+
+			return ex.NewNil()
+		},
+		Mod: &Mod{
+			Type: ModExec,
+			Exec: map[int]struct{}{},
+		},
+	},
+
+	"defined-symbols": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 0 {
+				return ex.NewFatal("defined-symbols: expected zero expressions")
+			}
+
+			exists := map[string]struct{}{}
+			var names []string
+			for vars := ir.varsEnvironment; vars != nil; vars = vars.Parent {
+				for _, name := range vars.Names() {
+					if _, ok := exists[name]; !ok {
+						exists[name] = struct{}{}
+						names = append(names, name)
+					}
+				}
+			}
+
+			sort.Strings(names)
+
+			res := make([]*ex.Expr, len(names))
+			for i, name := range names {
+				res[i] = ex.NewSymbol(name)
+			}
+
+			return ex.NewList(res...)
+		},
+	},
+
+	"provide": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("provide: must be 1 argument")
+			}
+
+			if args[0].Type != ex.Symbol {
+				return ex.NewFatal("provide: expected symbol, given " + args[0].ToString())
+			}
+
+			ir.features[args[0].String] = struct{}{}
+			return args[0]
+		},
+	},
+
+	"require": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 && len(args) != 2 {
+				return ex.NewFatal("require: expected 1 or 2 arguments")
+			}
+
+			for _, arg := range args {
+				if arg.Type != ex.Symbol {
+					return ex.NewFatal("require: expected symbol, given " + arg.ToString())
+				}
+			}
+
+			if _, ok := ir.features[args[0].String]; ok {
+				return quote(args[0])
+			}
+
+			// path to file is the name of feature by default
+			path := args[0]
+			if len(args) == 2 {
+				path = args[1]
+			}
+
+			return begin(ex.NewList(ex.NewFunction("load"), quote(path)), ex.NewList(ex.NewFunction("provide"), quote(args[0])))
+		},
+		Eval: true,
+	},
+
+	"module": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) < 1 {
+				return ex.NewFatal("module: must be at less 1 argument")
+			}
+
+			if args[0].Type != ex.Symbol {
+				return ex.NewFatal("module: expected symbol, given " + args[0].ToString())
+			}
+
+			// body is calculated in a new scope inside the root scope, so the module doesn't see local symbols of the
+			// caller and its definitions don't change other scopes
+			vars := ex.NewVarsWithParent(ir.globals)
+			ir.modules[args[0].String] = vars
+			ir.setNewVars(vars)
+
+			return begin(append(args[1:], quote(args[0]))...)
+		},
+		Mod: &Mod{
+			Type: ModExec,
+			Exec: map[int]struct{}{},
+		},
+		Eval: true,
+	},
+
+	"import": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("import: must be 1 argument")
+			}
+
+			if args[0].Type != ex.Symbol {
+				return ex.NewFatal("import: expected symbol, given " + args[0].ToString())
+			}
+
+			vars, ok := ir.modules[args[0].String]
+			if !ok {
+				return ex.NewFatal("import: module '" + args[0].String + "' is not defined")
+			}
+
+			for _, name := range vars.Names() {
+				expr, _ := vars.Get(name)
+				ir.bind(name, expr)
+			}
+
+			return args[0]
+		},
+		Mod: &Mod{
+			Type: ModExec,
+			Exec: map[int]struct{}{},
+		},
+	},
+
+	"module-ref": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 2 {
+				return ex.NewFatal("module-ref: must be 2 arguments")
+			}
+
+			for _, arg := range args {
+				if arg.Type != ex.Symbol {
+					return ex.NewFatal("module-ref: expected symbol, given " + arg.ToString())
+				}
+			}
+
+			vars, ok := ir.modules[args[0].String]
+			if !ok {
+				return ex.NewFatal("module-ref: module '" + args[0].String + "' is not defined")
+			}
+
+			expr, ok := vars.Get(args[1].String)
+			if !ok {
+				return ex.NewFatal("module-ref: symbol '" + args[1].String + "' is not defined in module '" + args[0].String + "'")
+			}
+
+			return expr
+		},
+		Mod: &Mod{
+			Type: ModExec,
+			Exec: map[int]struct{}{},
+		},
+	},
+
+	"capture-env": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 0 {
+				return ex.NewFatal("capture-env: expected zero expressions")
+			}
+
+			return ex.NewEnvironment(ir.varsEnvironment)
+		},
+	},
+
+	"eval-in": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 2 {
+				return ex.NewFatal("eval-in: must be 2 arguments")
+			}
+
+			if args[0].Type != ex.Environment {
+				return ex.NewFatal("eval-in: first argument must be an environment, given " + args[0].ToString())
+			}
+
+			// expression is calculated in a new scope inside the environment, so its definitions don't change the environment
+			return ex.NewClosure(ex.NewNil(), []*ex.Expr{args[1]}, args[0].ParentVars).ToList()
+		},
+		Eval: true,
+	},
+
+	"trace": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("trace: must be 1 argument")
+			}
+
+			if args[0].Type != ex.Symbol {
+				return ex.NewFatal("trace: argument is not symbol")
+			}
+
+			scope := ir.findScope(args[0].String)
+			if scope == nil {
+				return ex.NewFatal("trace: symbol '" + args[0].String + "' is not defined")
+			}
+
+			f, _ := scope.Get(args[0].String)
+			if fatal := callable("trace", f); fatal != nil {
+				return fatal
+			}
+
+			if _, ok := ir.traced[f]; ok {
+				return ex.NewFatal("trace: symbol '" + args[0].String + "' is already traced")
+			}
+
+			body := ex.NewList(ex.NewFunction("%trace"), quote(args[0]), f, ex.NewSymbol("args"))
+			tracing := ex.NewClosure(ex.NewSymbol("args"), []*ex.Expr{body}, ir.varsEnvironment)
+			ir.traced[tracing] = f
+			scope.Set(args[0].String, tracing)
+
+			return args[0]
+		},
+		Mod: &Mod{
+			Type: ModExec,
+			Exec: map[int]struct{}{},
+		},
+	},
+
+	"untrace": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("untrace: must be 1 argument")
+			}
+
+			if args[0].Type != ex.Symbol {
+				return ex.NewFatal("untrace: argument is not symbol")
+			}
+
+			scope := ir.findScope(args[0].String)
+			if scope == nil {
+				return ex.NewFatal("untrace: symbol '" + args[0].String + "' is not defined")
+			}
+
+			tracing, _ := scope.Get(args[0].String)
+			f, ok := ir.traced[tracing]
+			if !ok {
+				return ex.NewFatal("untrace: symbol '" + args[0].String + "' isn't traced")
+			}
+
+			delete(ir.traced, tracing)
+			scope.Set(args[0].String, f)
+
+			return args[0]
+		},
+		Mod: &Mod{
+			Type: ModExec,
+			Exec: map[int]struct{}{},
+		},
+	},
+
+	// (%trace name f args) writes call of traced function and calls it. Result is written by %trace-exit
+	"%trace": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			name, f, fArgs := args[0], args[1], args[2]
+
+			call := []*ex.Expr{f}
+			list, _ := fArgs.ToSlice()
+			for _, arg := range list {
+				call = append(call, quote(arg))
+			}
+
+			_, err := fmt.Fprintln(ir.stdout, strings.Repeat("  ", ir.traceDepth)+name.Cons(fArgs).ToString())
+			if err != nil {
+				return ex.NewFatal(err.Error())
+			}
+
+			ir.traceDepth++
+			return begin(ex.NewList(ex.NewFunction("%trace-exit"), ex.NewList(call...)))
+		},
+		Eval: true,
+	},
+
+	"%trace-exit": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			ir.traceDepth--
+
+			_, err := fmt.Fprintln(ir.stdout, strings.Repeat("  ", ir.traceDepth)+args[0].ToString())
+			if err != nil {
+				return ex.NewFatal(err.Error())
+			}
+
+			return args[0]
+		},
+	},
+
+	// (%letrec-define (quote (names...)) exprs...) defines names by values of exprs, which are calculated before
+	// the first definition
+	"%letrec-define": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) == 0 {
+				return ex.NewFatal("%letrec-define: must be at less 1 argument")
+			}
+
+			syms, ok := args[0].ToSlice()
+			if !ok || len(syms) != len(args)-1 {
+				return ex.NewFatal("%letrec-define: expected list of names for each value")
+			}
+
+			for i, sym := range syms {
+				value := args[i+1]
+				if value.Type == ex.Closure && value.String == "" {
+					value.String = sym.String
+				}
+
+				ir.bind(sym.String, value)
+			}
+
+			return ex.NewNil()
+		},
+	},
+
+	"letrec": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			return letrec("letrec", ir, args)
+		},
+		Mod: &Mod{
+			Type: ModExec,
+			Exec: map[int]struct{}{},
+		},
+		Eval: true,
+	},
+
+	"letrec*": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			return letrec("letrec*", ir, args)
+		},
+		Mod: &Mod{
+			Type: ModExec,
+			Exec: map[int]struct{}{},
+		},
+		Eval: true,
+	},
+
+	"lambda": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) < 2 {
+				return ex.NewFatal("lambda: must be at less 2 arguments")
+			}
+
+			return ex.NewClosure(args[0], args[1:], ir.varsEnvironment)
+		},
+		Mod: &Mod{
+			Type: ModExec,
+			Exec: map[int]struct{}{},
+		},
+	},
+
+	"delay": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("delay: must be 1 argument")
+			}
+
+			return ex.NewPromise(args[0], ir.varsEnvironment)
+		},
+		Mod: &Mod{
+			Type: ModExec,
+			Exec: map[int]struct{}{},
+		},
+	},
+
+	"force": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("force: must be 1 argument")
+			}
+
+			promise := args[0]
+			if promise.Type != ex.Promise {
+				return begin(quote(promise))
+			}
+
+			if promise.Res != nil {
+				return begin(quote(promise.Res))
+			}
+
+			thunk := ex.NewClosure(ex.NewNil(), []*ex.Expr{promise.PromiseExpr()}, promise.ParentVars)
+			return ex.NewList(ex.NewFunction("%promise-set"), promise, thunk.ToList())
+		},
+		Eval: true,
+	},
+
+	"%promise-set": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			// promise could be forced while its expression was calculated, the first result is kept
+			if args[0].Res == nil {
+				args[0].Res = args[1]
+			}
+
+			return args[0].Res
+		},
+	},
+
+	"cons-stream": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 2 {
+				return ex.NewFatal("cons-stream: must be 2 arguments")
+			}
+
+			return ex.NewList(args[0], ex.NewPromise(args[1], ir.varsEnvironment))
+		},
+		Mod: &Mod{
+			Type: ModExec,
+			Exec: map[int]struct{}{1: {}},
+		},
+	},
+
+	"stream-car": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("stream-car: must be 1 argument")
+			}
+
+			if fatal := stream("stream-car", args[0]); fatal != nil {
+				return fatal
+			}
+
+			return args[0].Car()
+		},
+	},
+
+	"stream-cdr": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("stream-cdr: must be 1 argument")
+			}
+
+			if fatal := stream("stream-cdr", args[0]); fatal != nil {
+				return fatal
+			}
+
+			return begin(ex.NewList(ex.NewFunction("force"), args[0].Index(1)))
+		},
+		Eval: true,
+	},
+
+	"stream-take": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 2 {
+				return ex.NewFatal("stream-take: must be 2 arguments")
+			}
+
+			n, fatal := natural("stream-take", args[1])
+			if fatal != nil {
+				return fatal
+			}
+
+			if n == 0 || args[0].IsNil() {
+				return begin(ex.NewNil())
+			}
+
+			if fatal := stream("stream-take", args[0]); fatal != nil {
+				return fatal
+			}
+
+			// tail after the last taken element isn't forced
+			if n == 1 {
+				return begin(quote(args[0].Car().ToList()))
+			}
+
+			tail := ex.NewList(ex.NewFunction("stream-cdr"), quote(args[0]))
+			return begin(ex.NewList(ex.NewFunction("cons"), quote(args[0].Car()),
+				ex.NewList(ex.NewFunction("stream-take"), tail, ex.NewNumber(float64(n-1)))))
+		},
+		Eval: true,
+	},
+
+	"stream-ref": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 2 {
+				return ex.NewFatal("stream-ref: must be 2 arguments")
+			}
+
+			k, fatal := natural("stream-ref", args[1])
+			if fatal != nil {
+				return fatal
+			}
+
+			if fatal := stream("stream-ref", args[0]); fatal != nil {
+				return fatal
+			}
+
+			if k == 0 {
+				return begin(quote(args[0].Car()))
+			}
+
+			tail := ex.NewList(ex.NewFunction("stream-cdr"), quote(args[0]))
+			return begin(ex.NewList(ex.NewFunction("stream-ref"), tail, ex.NewNumber(float64(k-1))))
+		},
+		Eval: true,
+	},
+
+	"begin": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) == 0 {
+				return ex.NewNil()
+			}
+			return args[len(args)-1]
+		},
+	},
+
+	"begin0": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) == 0 {
+				return ex.NewFatal("begin0: must be at less 1 argument")
+			}
+			return args[0]
+		},
+	},
+
+	"or": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			for _, arg := range args {
+				if !ir.isFalse(arg) {
+					return arg
+				}
+			}
+
+			return ex.NewNil()
+		},
+		Mod: &Mod{
+			Type: ModOr,
+		},
+	},
+
+	"and": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) == 0 {
+				return ex.NewT()
+			}
+
+			return args[len(args)-1]
+		},
+		Mod: &Mod{
+			Type: ModAnd,
+		},
+	},
+
+	"if": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 2 && len(args) != 3 {
+				return ex.NewFatal(fmt.Sprintf("if: expected 2 or 3 expressions, got %d", len(args)))
+			}
+
+			if !ir.isFalse(args[0]) {
+				return args[1]
+			}
+
+			if len(args) == 2 {
+				return ex.NewNil()
+			}
+
+			return args[2]
+		},
+		Mod: &Mod{
+			Type: ModIf,
+		},
+	},
+
+	"cond": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			code := ex.NewNil()
+			for i := len(args) - 1; i >= 0; i-- {
+				clause, ok := args[i].ToSlice()
+				if !ok || len(clause) == 0 {
+					return ex.NewFatal("cond: clause must be a non-empty list")
+				}
+
+				test := clause[0]
+				if test.Type == ex.Symbol && test.String == "else" {
+					test = ex.NewT()
+				}
+
+				switch {
+				case len(clause) == 1:
+					code = ex.NewList(ex.NewFunction("%cond"), test, ex.NewFunction("identity"), code)
+				case clause[1].Type == ex.Symbol && clause[1].String == "=>":
+					if len(clause) != 3 {
+						return ex.NewFatal("cond: expected one expression after =>")
+					}
+
+					code = ex.NewList(ex.NewFunction("%cond"), test, clause[2], code)
+				default:
+					code = ex.NewList(ex.NewFunction("if"), test, begin(clause[1:]...), code)
+				}
+			}
+
+			return begin(code)
+		},
+		Mod: &Mod{
+			Type: ModExec,
+			Exec: map[int]struct{}{},
+		},
+		Eval: true,
+	},
+
+	"%cond": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if ir.isFalse(args[0]) {
+				return begin(args[2])
+			}
+
+			return begin(ex.NewList(args[1], quote(args[0])))
+		},
+		Mod: &Mod{
+			Type: ModExec,
+			Exec: map[int]struct{}{1: {}},
+		},
+		Eval: true,
+	},
+
+	"case": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) == 0 {
+				return ex.NewFatal("case: must be at less 1 argument")
+			}
+
+			key := args[0]
+			for _, arg := range args[1:] {
+				clause, ok := arg.ToSlice()
+				if !ok || len(clause) < 2 {
+					return ex.NewFatal("case: clause must be a list of data and body")
+				}
+
+				matched := clause[0].Type == ex.Symbol && clause[0].String == "else"
+				if !matched {
+					data, ok := clause[0].ToSlice()
+					if !ok {
+						return ex.NewFatal("case: data must be a list or else")
+					}
+
+					for _, datum := range data {
+						if caseMatch(key, datum) {
+							matched = true
+							break
+						}
+					}
+				}
+
+				if !matched {
+					continue
+				}
+
+				if clause[1].Type == ex.Symbol && clause[1].String == "=>" {
+					if len(clause) != 3 {
+						return ex.NewFatal("case: expected one expression after =>")
+					}
+
+					return begin(ex.NewList(clause[2], quote(key)))
+				}
+
+				return begin(clause[1:]...)
+			}
+
+			return begin(ex.NewNil())
+		},
+		Mod: &Mod{
+			Type: ModExec,
+			Exec: map[int]struct{}{1: {}},
+		},
+		Eval: true,
+	},
+
+	"if-let": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 2 && len(args) != 3 {
+				return ex.NewFatal(fmt.Sprintf("if-let: expected 2 or 3 expressions, got %d", len(args)))
+			}
+
+			name, expr, fatal := bindingForm("if-let", args[0])
+			if fatal != nil {
+				return fatal
+			}
+
+			branch := ex.NewList(ex.NewFunction("if"), name, args[1])
+			if len(args) == 3 {
+				branch = ex.NewList(ex.NewFunction("if"), name, args[1], args[2])
+			}
+
+			return ex.NewList(ex.NewClosure(name.ToList(), []*ex.Expr{branch}, ir.varsEnvironment), expr)
+		},
+		Mod: &Mod{
+			Type: ModExec,
+			Exec: map[int]struct{}{},
+		},
+		Eval: true,
+	},
+
+	"when-let": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) < 2 {
+				return ex.NewFatal("when-let: must be at less 2 arguments")
+			}
+
+			name, expr, fatal := bindingForm("when-let", args[0])
+			if fatal != nil {
+				return fatal
+			}
+
+			body := ex.NewFunction("begin").Cons(ex.NewList(args[1:]...))
+			branch := ex.NewList(ex.NewFunction("if"), name, body)
+
+			return ex.NewList(ex.NewClosure(name.ToList(), []*ex.Expr{branch}, ir.varsEnvironment), expr)
+		},
+		Mod: &Mod{
+			Type: ModExec,
+			Exec: map[int]struct{}{},
+		},
+		Eval: true,
+	},
+
+	// multiple values are represented by list of them
+	"values": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			return ex.NewList(args...)
+		},
+	},
+
+	"call-with-values": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 2 {
+				return ex.NewFatal("call-with-values: must be 2 arguments")
+			}
+
+			for _, arg := range args {
+				if fatal := callable("call-with-values", arg); fatal != nil {
+					return fatal
+				}
+			}
+
+			return ex.NewList(ex.NewFunction("%call-with-values"), args[1], args[0].ToList())
+		},
+		Eval: true,
+	},
+
+	"%call-with-values": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			values, ok := args[1].ToSlice()
+			if !ok {
+				return ex.NewFatal("call-with-values: producer must return values, given " + args[1].ToString())
+			}
+
+			call := []*ex.Expr{args[0]}
+			for _, value := range values {
+				call = append(call, quote(value))
+			}
+
+			return begin(ex.NewList(call...))
+		},
+		Eval: true,
+	},
+
+	"identity": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("identity: must be 1 argument")
+			}
+
+			return args[0]
+		},
+	},
+
+	"const": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("const: must be 1 argument")
+			}
+
+			return ex.NewClosure(ex.NewSymbol("args"), []*ex.Expr{quote(args[0])}, ir.varsEnvironment)
+		},
+	},
+
+	"find": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 2 {
+				return ex.NewFatal("find: must be 2 arguments")
+			}
+
+			if fatal := callable("find", args[0]); fatal != nil {
+				return fatal
+			}
+
+			list, ok := args[1].ToSlice()
+			if !ok {
+				return ex.NewFatal("find: second argument must be a list")
+			}
+
+			code := ex.NewNil()
+			for i := len(list) - 1; i >= 0; i-- {
+				code = ex.NewList(ex.NewFunction("if"), ex.NewList(args[0], quote(list[i])), quote(list[i]), code)
+			}
+
+			return begin(code)
+		},
+		Eval: true,
+	},
+
+	"list-index": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 2 {
+				return ex.NewFatal("list-index: must be 2 arguments")
+			}
+
+			if fatal := callable("list-index", args[0]); fatal != nil {
+				return fatal
+			}
+
+			list, ok := args[1].ToSlice()
+			if !ok {
+				return ex.NewFatal("list-index: second argument must be a list")
+			}
+
+			code := ex.NewNil()
+			for i := len(list) - 1; i >= 0; i-- {
+				code = ex.NewList(ex.NewFunction("if"), ex.NewList(args[0], quote(list[i])), ex.NewNumber(float64(i)), code)
+			}
+
+			return begin(code)
+		},
+		Eval: true,
+	},
+
+	"take": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 2 {
+				return ex.NewFatal("take: must be 2 arguments")
+			}
+
+			list, ok := args[0].ToSlice()
+			if !ok {
+				return ex.NewFatal("take: first argument must be a list")
+			}
+
+			n, fatal := natural("take", args[1])
+			if fatal != nil {
+				return fatal
+			}
+
+			if n > len(list) {
+				n = len(list)
+			}
+
+			return ex.NewList(list[:n]...)
+		},
+	},
+
+	"drop": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 2 {
+				return ex.NewFatal("drop: must be 2 arguments")
+			}
+
+			if args[0].Type != ex.Pair && args[0].Type != ex.Nil {
+				return ex.NewFatal("drop: first argument must be a list")
+			}
+
+			n, fatal := natural("drop", args[1])
+			if fatal != nil {
+				return fatal
+			}
+
+			res := args[0]
+			for ; n > 0 && res.Type == ex.Pair; n-- {
+				res = res.Cdr()
+			}
+
+			return res
+		},
+	},
+
+	"iota": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) < 1 || len(args) > 3 {
+				return ex.NewFatal("iota: expected from 1 to 3 arguments")
+			}
+
+			count, fatal := natural("iota", args[0])
+			if fatal != nil {
+				return fatal
+			}
+
+			start, step := 0.0, 1.0
+			for i, arg := range args[1:] {
+				if arg.Type != ex.Number {
+					return ex.NewFatal("iota: expected numbers")
+				}
+
+				if i == 0 {
+					start = arg.Number
+				} else {
+					step = arg.Number
+				}
+			}
+
+			res := ex.NewNil()
+			for i := count - 1; i >= 0; i-- {
+				res = ex.NewNumber(start + float64(i)*step).Cons(res)
+			}
+
+			return res
+		},
+	},
+
+	"make-list": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 && len(args) != 2 {
+				return ex.NewFatal("make-list: expected 1 or 2 arguments")
+			}
+
+			n, fatal := natural("make-list", args[0])
+			if fatal != nil {
+				return fatal
+			}
+
+			fill := ex.NewNil()
+			if len(args) == 2 {
+				fill = args[1]
+			}
+
+			res := ex.NewNil()
+			for ; n > 0; n-- {
+				res = fill.Cons(res)
+			}
+
+			return res
+		},
+	},
+
+	"count": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 2 {
+				return ex.NewFatal("count: must be 2 arguments")
+			}
+
+			if fatal := callable("count", args[0]); fatal != nil {
+				return fatal
+			}
+
+			list, ok := args[1].ToSlice()
+			if !ok {
+				return ex.NewFatal("count: second argument must be a list")
+			}
+
+			code := []*ex.Expr{ex.NewFunction("+")}
+			for _, elem := range list {
+				code = append(code, ex.NewList(ex.NewFunction("if"), ex.NewList(args[0], quote(elem)), ex.NewNumber(1), ex.NewNumber(0)))
+			}
+
+			return begin(ex.NewList(code...))
+		},
+		Eval: true,
+	},
+
+	// call of function is evaluated in place of apply, so apply in tail position doesn't grow the stack
+	"apply": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) < 2 {
+				return ex.NewFatal("apply: must be at less 2 arguments")
+			}
+
+			if fatal := callable("apply", args[0]); fatal != nil {
+				return fatal
+			}
+
+			list, ok := args[len(args)-1].ToSlice()
+			if !ok {
+				return ex.NewFatal("apply: last argument must be a list")
+			}
+
+			call := []*ex.Expr{args[0]}
+			for _, arg := range append(args[1:len(args)-1], list...) {
+				call = append(call, quote(arg))
+			}
+
+			return begin(ex.NewList(call...))
+		},
+		Eval: true,
+	},
+
+	"map": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) < 2 {
+				return ex.NewFatal("map: must be at less 2 arguments")
+			}
+
+			if fatal := callable("map", args[0]); fatal != nil {
+				return fatal
+			}
+
+			lists := make([][]*ex.Expr, len(args)-1)
+			for i, arg := range args[1:] {
+				list, ok := arg.ToSlice()
+				if !ok {
+					return ex.NewFatal("map: expected lists, given " + arg.ToString())
+				}
+
+				lists[i] = list
+			}
+
+			return begin(listCode(calls(args[0], lists)))
+		},
+		Eval: true,
+	},
+
+	"partition": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 2 {
+				return ex.NewFatal("partition: must be 2 arguments")
+			}
+
+			if fatal := callable("partition", args[0]); fatal != nil {
+				return fatal
+			}
+
+			list, ok := args[1].ToSlice()
+			if !ok {
+				return ex.NewFatal("partition: second argument must be a list")
+			}
+
+			return begin(ex.NewList(ex.NewFunction("%partition"), quote(args[1]), listCode(calls(args[0], [][]*ex.Expr{list}))))
+		},
+		Eval: true,
+	},
+
+	// (%partition list results) splits list by results of predicate
+	"%partition": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			list, _ := args[0].ToSlice()
+			results, _ := args[1].ToSlice()
+
+			var in, out []*ex.Expr
+			for i, elem := range list {
+				if ir.isFalse(results[i]) {
+					out = append(out, elem)
+				} else {
+					in = append(in, elem)
+				}
+			}
+
+			return ex.NewList(ex.NewList(in...), ex.NewList(out...))
+		},
+	},
+
+	"group-by": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 2 {
+				return ex.NewFatal("group-by: must be 2 arguments")
+			}
+
+			if fatal := callable("group-by", args[0]); fatal != nil {
+				return fatal
+			}
+
+			list, ok := args[1].ToSlice()
+			if !ok {
+				return ex.NewFatal("group-by: second argument must be a list")
+			}
+
+			return begin(ex.NewList(ex.NewFunction("%group-by"), quote(args[1]), listCode(calls(args[0], [][]*ex.Expr{list}))))
+		},
+		Eval: true,
+	},
+
+	// (%group-by list keys) returns association list of keys and lists of elements in order of the first occurrence of keys
+	"%group-by": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			list, _ := args[0].ToSlice()
+			keys, _ := args[1].ToSlice()
+
+			var groupKeys []*ex.Expr
+			var groups [][]*ex.Expr
+			for i, elem := range list {
+				j := 0
+				for j < len(groupKeys) && !groupKeys[j].Equal(keys[i]) {
+					j++
+				}
+
+				if j == len(groupKeys) {
+					groupKeys = append(groupKeys, keys[i])
+					groups = append(groups, nil)
+				}
+
+				groups[j] = append(groups[j], elem)
+			}
+
+			res := make([]*ex.Expr, len(groups))
+			for i, group := range groups {
+				res[i] = ex.NewList(groupKeys[i], ex.NewList(group...))
+			}
+
+			return ex.NewList(res...)
+		},
+	},
+
+	"delete-duplicates": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 && len(args) != 2 {
+				return ex.NewFatal("delete-duplicates: expected 1 or 2 arguments")
+			}
+
+			list, ok := args[0].ToSlice()
+			if !ok {
+				return ex.NewFatal("delete-duplicates: first argument must be a list")
+			}
+
+			if len(args) == 2 {
+				if fatal := callable("delete-duplicates", args[1]); fatal != nil {
+					return fatal
+				}
+
+				if len(list) == 0 {
+					return begin(ex.NewNil())
+				}
+
+				return begin(ex.NewList(ex.NewFunction("%delete-duplicates"), args[1], ex.NewNil(), quote(args[0]), ex.NewNil()))
+			}
+
+			var res []*ex.Expr
+			for _, elem := range list {
+				duplicate := false
+				for _, kept := range res {
+					if kept.Equal(elem) {
+						duplicate = true
+						break
+					}
+				}
+
+				if !duplicate {
+					res = append(res, elem)
+				}
+			}
+
+			return begin(quote(ex.NewList(res...)))
+		},
+		Eval: true,
+	},
+
+	// (%delete-duplicates equal kept rest duplicate) keeps head of rest unless it is duplicate and checks the next element
+	// of rest by comparing it with kept elements
+	"%delete-duplicates": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			equal, kept, rest, duplicate := args[0], args[1], args[2], args[3]
+
+			if ir.isFalse(duplicate) {
+				list, _ := kept.ToSlice()
+				kept = ex.NewList(append(list, rest.Car())...)
+			}
+
+			rest = rest.Cdr()
+			if rest.IsNil() {
+				return begin(quote(kept))
+			}
+
+			check := []*ex.Expr{ex.NewFunction("or")}
+			list, _ := kept.ToSlice()
+			for _, elem := range list {
+				check = append(check, ex.NewList(equal, quote(elem), quote(rest.Car())))
+			}
+
+			return begin(ex.NewList(ex.NewFunction("%delete-duplicates"), equal, quote(kept), quote(rest), ex.NewList(check...)))
+		},
+		Eval: true,
+	},
+
+	"assoc-set": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 3 {
+				return ex.NewFatal("assoc-set: must be 3 arguments")
+			}
+
+			alist, ok := args[0].ToSlice()
+			if !ok {
+				return ex.NewFatal("assoc-set: first argument must be a list")
+			}
+
+			entry := ex.NewList(args[1], args[2])
+			for i, pair := range alist {
+				if pair.Type != ex.Pair {
+					return ex.NewFatal("assoc-set: all elements of association list must be a pairs")
+				}
+
+				if pair.Car().Equal(args[1]) {
+					tail := args[0]
+					for j := 0; j <= i; j++ {
+						tail = tail.Cdr()
+					}
+
+					res := entry.Cons(tail)
+					for j := i - 1; j >= 0; j-- {
+						res = alist[j].Cons(res)
+					}
+
+					return res
+				}
+			}
+
+			return entry.Cons(args[0])
+		},
+	},
+
+	"assoc-remove": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 2 {
+				return ex.NewFatal("assoc-remove: must be 2 arguments")
+			}
+
+			alist, ok := args[0].ToSlice()
+			if !ok {
+				return ex.NewFatal("assoc-remove: first argument must be a list")
+			}
+
+			var res []*ex.Expr
+			for _, pair := range alist {
+				if pair.Type != ex.Pair {
+					return ex.NewFatal("assoc-remove: all elements of association list must be a pairs")
+				}
+
+				if !pair.Car().Equal(args[1]) {
+					res = append(res, pair)
+				}
+			}
+
+			return ex.NewList(res...)
+		},
+	},
+
+	"append!": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 2 {
+				return ex.NewFatal("append!: must be 2 arguments")
+			}
+
+			if args[1].Type != ex.Pair && args[1].Type != ex.Nil {
+				return ex.NewFatal("append!: second argument must be a list")
+			}
+
+			if args[0].IsNil() {
+				return args[1]
+			}
+
+			last, fatal := lastPair("append!", args[0])
+			if fatal != nil {
+				return fatal
+			}
+
+			last.SetCdr(args[1])
+			return args[0]
+		},
+	},
+
+	"reverse!": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("reverse!: must be 1 argument")
+			}
+
+			if args[0].IsNil() {
+				return args[0]
+			}
+
+			if _, fatal := lastPair("reverse!", args[0]); fatal != nil {
+				return fatal
+			}
+
+			res, cur := ex.NewNil(), args[0]
+			for cur.Type == ex.Pair {
+				next := cur.Cdr()
+				cur.SetCdr(res)
+				res, cur = cur, next
+			}
+
+			return res
+		},
+	},
+
+	"flatten": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("flatten: must be 1 argument")
+			}
+
+			if args[0].Type != ex.Pair && args[0].Type != ex.Nil {
+				return ex.NewFatal("flatten: argument must be a list")
+			}
+
+			return ex.NewList(flatten(args[0], nil)...)
+		},
+	},
+
+	"list-max": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			return listExtreme("list-max", args, func(a, b float64) bool { return a > b })
+		},
+	},
+
+	"list-min": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			return listExtreme("list-min", args, func(a, b float64) bool { return a < b })
+		},
+	},
+
+	"memoize": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("memoize: must be 1 argument")
+			}
+
+			if fatal := callable("memoize", args[0]); fatal != nil {
+				return fatal
+			}
+
+			// car of cache is association list of arguments and results
+			cache := ex.NewNil().ToList()
+			body := ex.NewList(ex.NewFunction("%memo"), quote(cache), args[0], ex.NewSymbol("args"))
+
+			return ex.NewClosure(ex.NewSymbol("args"), []*ex.Expr{body}, ir.varsEnvironment)
+		},
+	},
+
+	"%memo": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			cache, f, fArgs := args[0], args[1], args[2]
+
+			for entries := cache.Car(); entries.Type == ex.Pair; entries = entries.Cdr() {
+				if entries.Car().Car().Equal(fArgs) {
+					return begin(quote(entries.Car().Index(1)))
+				}
+			}
+
+			call := []*ex.Expr{f}
+			list, _ := fArgs.ToSlice()
+			for _, arg := range list {
+				call = append(call, quote(arg))
+			}
+
+			return begin(ex.NewList(ex.NewFunction("%memo-set"), quote(cache), quote(fArgs), ex.NewList(call...)))
+		},
+		Eval: true,
+	},
+
+	"%memo-set": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			cache, fArgs, res := args[0], args[1], args[2]
+			cache.SetCar(ex.NewList(fArgs, res).Cons(cache.Car()))
+
+			return res
+		},
+	},
+
+	"zip": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) == 0 {
+				return ex.NewFatal("zip: must be at less 1 argument")
+			}
+
+			lists := make([][]*ex.Expr, len(args))
+			length := -1
+			for i, arg := range args {
+				list, ok := arg.ToSlice()
+				if !ok {
+					return ex.NewFatal("zip: expected lists, given " + arg.ToString())
+				}
+
+				if length == -1 || len(list) < length {
+					length = len(list)
+				}
+
+				lists[i] = list
+			}
+
+			res := make([]*ex.Expr, length)
+			for i := range res {
+				tuple := make([]*ex.Expr, len(lists))
+				for j, list := range lists {
+					tuple[j] = list[i]
+				}
+
+				res[i] = ex.NewList(tuple...)
+			}
+
+			return ex.NewList(res...)
+		},
+	},
+
+	"unzip": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("unzip: must be 1 argument")
+			}
+
+			tuples, ok := args[0].ToSlice()
+			if !ok {
+				return ex.NewFatal("unzip: argument must be a list")
+			}
+
+			if len(tuples) == 0 {
+				return ex.NewNil()
+			}
+
+			var lists [][]*ex.Expr
+			for i, tuple := range tuples {
+				elems, ok := tuple.ToSlice()
+				if !ok || i > 0 && len(elems) != len(lists) {
+					return ex.NewFatal("unzip: expected lists of the same length, given " + tuple.ToString())
+				}
+
+				if i == 0 {
+					lists = make([][]*ex.Expr, len(elems))
+				}
+
+				for j, elem := range elems {
+					lists[j] = append(lists[j], elem)
+				}
+			}
+
+			res := make([]*ex.Expr, len(lists))
+			for i, list := range lists {
+				res[i] = ex.NewList(list...)
+			}
+
+			return ex.NewList(res...)
+		},
+	},
+
+	"vector": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			return ex.NewVector(args...)
+		},
+	},
+
+	"vector?": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("vector?: must be 1 argument")
+			}
+
+			if args[0].Type == ex.Vector {
+				return ex.NewT()
+			}
+
+			return ex.NewNil()
+		},
+	},
+
+	"vector-ref": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 2 {
+				return ex.NewFatal("vector-ref: must be 2 arguments")
+			}
+
+			if args[0].Type != ex.Vector {
+				return ex.NewFatal("vector-ref: first argument must be a vector")
+			}
+
+			k, fatal := natural("vector-ref", args[1])
+			if fatal != nil {
+				return fatal
+			}
+
+			if k >= len(args[0].Vector) {
+				return ex.NewFatal(fmt.Sprintf("vector-ref: index %d is out of range of vector of length %d", k, len(args[0].Vector)))
+			}
+
+			return args[0].Vector[k]
+		},
+	},
+
+	"vector->list": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) < 1 || len(args) > 3 {
+				return ex.NewFatal("vector->list: expected from 1 to 3 arguments")
+			}
+
+			if args[0].Type != ex.Vector {
+				return ex.NewFatal("vector->list: first argument must be a vector")
+			}
+
+			start, end, fatal := vectorRange("vector->list", args[0], args[1:])
+			if fatal != nil {
+				return fatal
+			}
+
+			return ex.NewList(args[0].Vector[start:end]...)
+		},
+	},
+
+	"list->vector": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("list->vector: must be 1 argument")
+			}
+
+			list, ok := args[0].ToSlice()
+			if !ok {
+				return ex.NewFatal("list->vector: argument must be a list")
+			}
+
+			return ex.NewVector(list...)
+		},
+	},
+
+	"vector-fill!": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 2 {
+				return ex.NewFatal("vector-fill!: must be 2 arguments")
+			}
+
+			if args[0].Type != ex.Vector {
+				return ex.NewFatal("vector-fill!: first argument must be a vector")
+			}
+
+			for i := range args[0].Vector {
+				args[0].Vector[i] = args[1]
+			}
+
+			return args[0]
+		},
+	},
+
+	"vector-copy": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) < 1 || len(args) > 3 {
+				return ex.NewFatal("vector-copy: expected from 1 to 3 arguments")
+			}
+
+			if args[0].Type != ex.Vector {
+				return ex.NewFatal("vector-copy: first argument must be a vector")
+			}
+
+			start, end, fatal := vectorRange("vector-copy", args[0], args[1:])
+			if fatal != nil {
+				return fatal
+			}
+
+			return ex.NewVector(args[0].Vector[start:end]...)
+		},
+	},
+
+	"vector-map": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			vectors, fatal := mappedVectors("vector-map", args)
+			if fatal != nil {
+				return fatal
+			}
+
+			return begin(ex.NewList(ex.NewFunction("list->vector"), listCode(calls(args[0], vectors))))
+		},
+		Eval: true,
+	},
+
+	"vector-for-each": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			vectors, fatal := mappedVectors("vector-for-each", args)
+			if fatal != nil {
+				return fatal
+			}
+
+			return begin(append(calls(args[0], vectors), ex.NewNil())...)
+		},
+		Eval: true,
+	},
+
+	">": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 2 {
+				return ex.NewFatal(fmt.Sprintf(">: expected 2 expressions, got %d", len(args)))
+			}
+
+			for _, arg := range args {
+				if arg.Type != ex.Number {
+					return ex.NewFatal(">: expected numbers")
+				}
+			}
+
+			if args[0].Number > args[1].Number {
+				return ex.NewT()
+			}
+
+			return ex.NewNil()
+		},
+	},
+
+	"<": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 2 {
+				return ex.NewFatal(fmt.Sprintf("<: expected 2 expressions, got %d", len(args)))
+			}
+
+			for _, arg := range args {
+				if arg.Type != ex.Number {
+					return ex.NewFatal("<: expected numbers")
+				}
+			}
+
+			if args[0].Number < args[1].Number {
+				return ex.NewT()
+			}
+
+			return ex.NewNil()
+		},
+	},
+
+	"=": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) < 2 {
+				return ex.NewFatal(fmt.Sprintf("=: expected at less 2 expressions, got %d", len(args)))
+			}
+
+			// numbers are compared only with numbers regardless of their positions
+			numeric := false
+			for _, arg := range args {
+				numeric = numeric || arg.Type == ex.Number
+			}
+
+			if numeric {
+				for _, arg := range args {
+					if arg.Type != ex.Number {
+						return ex.NewFatal("=: expected numbers, given " + given(arg))
+					}
+				}
+
+				for i := 1; i < len(args); i++ {
+					if args[i-1].Number != args[i].Number {
+						return ex.NewNil()
+					}
+				}
+
+				return ex.NewT()
+			}
+
+			cur := args[0]
+			for _, arg := range args[1:] {
+				if !cur.Equal(arg) {
+					return ex.NewNil()
+				}
+			}
+
+			return ex.NewT()
+		},
+	},
 
-	"eval": {
+	"approx=": {
 		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
-			if len(args) != 1 {
-				return ex.NewFunction("begin").Cons(ex.NewFatal("quote: must be 1 argument").ToList())
+			if len(args) != 3 {
+				return ex.NewFatal("approx=: must be 3 arguments")
 			}
 
-			return ex.NewFunction("begin").Cons(args[0].ToList())
+			for _, arg := range args {
+				if arg.Type != ex.Number {
+					return ex.NewFatal("approx=: expected numbers, given " + given(arg))
+				}
+			}
+
+			if math.Abs(args[0].Number-args[1].Number) <= args[2].Number {
+				return ex.NewT()
+			}
+
+			return ex.NewNil()
 		},
 	},
 
-	"quote": {
+	"equal?": {
 		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
-			if len(args) != 1 {
-				return ex.NewFatal("quote: must be 1 argument")
+			if len(args) != 2 {
+				return ex.NewFatal("equal?: must be 2 arguments")
 			}
 
-			return args[0]
-		},
-		Mod: &Mod{
-			Type: ModExec,
-			Exec: map[int]struct{}{},
+			if args[0].Equal(args[1]) {
+				return ex.NewT()
+			}
+
+			return ex.NewNil()
 		},
 	},
 
-	"catch": {
+	// eq? compares objects by identity. Symbols of program are interned, so they are the same objects
+	"eq?": {
 		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
-			if len(args) == 0 {
-				return ex.NewFatal("catch: must be at least one argument")
+			if len(args) != 2 {
+				return ex.NewFatal("eq?: must be 2 arguments")
 			}
 
-			if args[0].Type == ex.Fatal {
-				panic("catch: it shouldn't have happened")
+			a, b := args[0], args[1]
+			if a == b || a.Type == ex.Nil && b.Type == ex.Nil || a.Type == ex.Number && b.Type == ex.Number && a.Number == b.Number {
+				return ex.NewT()
 			}
 
-			return args[0]
-		},
-		Mod: &Mod{
-			Type: ModTry,
+			return ex.NewNil()
 		},
 	},
 
-	"throw": {
+	"not": {
 		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
-			if len(args) != 1 && len(args) != 2 {
-				return ex.NewFatal("throw: must be one or two arguments")
-			}
-
-			if args[0].Type != ex.Symbol {
-				return ex.NewFatal("throw: first argument must be a symbol")
+			if len(args) != 1 {
+				return ex.NewFatal("not: must be 1 argument")
 			}
 
-			if len(args) == 2 {
-				return ex.NewFatal(args[0].String, args[1])
+			if ir.isFalse(args[0]) {
+				return ex.NewT()
 			}
 
-			return ex.NewFatal(args[0].String)
+			return ex.NewNil()
 		},
 	},
 
-	"car": {
+	"pair?": {
 		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
 			if len(args) != 1 {
-				return ex.NewFatal("car: must be 1 argument")
+				return ex.NewFatal("pair?: must be 1 argument")
 			}
 
-			return args[0].Car()
+			if args[0].Type == ex.Pair {
+				return ex.NewT()
+			}
+
+			return ex.NewNil()
 		},
 	},
 
-	"cdr": {
+	"number?": {
 		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
 			if len(args) != 1 {
-				return ex.NewFatal("cdr: must be 1 argument")
+				return ex.NewFatal("number?: must be 1 argument")
 			}
 
-			return args[0].Cdr()
+			if args[0].Type == ex.Number {
+				return ex.NewT()
+			}
+
+			return ex.NewNil()
 		},
 	},
 
-	"cons": {
+	"symbol?": {
 		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
-			if len(args) != 2 {
-				return ex.NewFatal("cons: must be 2 arguments")
+			if len(args) != 1 {
+				return ex.NewFatal("symbol?: must be 1 argument")
 			}
 
-			return args[0].Cons(args[1])
+			if args[0].Type == ex.Symbol {
+				return ex.NewT()
+			}
+
+			return ex.NewNil()
 		},
 	},
 
-	"define": {
+	"len": {
 		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
-			if len(args) != 2 {
-				return ex.NewFatal("define: must be 2 arguments")
+			if len(args) != 1 {
+				return ex.NewFatal("len: must be 1 argument")
 			}
 
 			if args[0].Type != ex.Symbol {
-				return ex.NewFatal("define: first argument is not a symbol")
+				return ex.NewFatal("len: must be a symbol")
 			}
 
-			ir.varsEnvironment.CurSymbols[args[0].String] = args[1]
-			return args[1]
+			return ex.NewNumber(float64(len([]rune(args[0].String))))
 		},
-		Mod: &Mod{
-			Type: ModExec,
-			Exec: map[int]struct{}{2: {}},
+	},
+
+	"string-trim": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			return trim("string-trim", args, strings.TrimSpace, strings.Trim)
 		},
 	},
 
-	"defmacro": {
+	"string-trim-left": {
 		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
-			if len(args) < 3 {
-				return ex.NewFatal("defmacro: must be at less 3 arguments")
+			return trim("string-trim-left", args, func(s string) string {
+				return strings.TrimLeftFunc(s, unicode.IsSpace)
+			}, strings.TrimLeft)
+		},
+	},
+
+	"string-trim-right": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			return trim("string-trim-right", args, func(s string) string {
+				return strings.TrimRightFunc(s, unicode.IsSpace)
+			}, strings.TrimRight)
+		},
+	},
+
+	"string-index": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 2 {
+				return ex.NewFatal("string-index: must be 2 arguments")
 			}
 
-			if args[0].Type != ex.Symbol {
-				return ex.NewFatal("defmacro: second argument is not a symbol")
+			for _, arg := range args {
+				if arg.Type != ex.Symbol {
+					return ex.NewFatal("string-index: expected symbols, given " + arg.ToString())
+				}
 			}
 
-			macro := ex.NewMacro(args[1], args[2:], ir.varsEnvironment)
-			ir.varsEnvironment.CurSymbols[args[0].String] = macro
+			// index is counted in runes like in len and -
+			i := strings.Index(args[0].String, args[1].String)
+			if i == -1 {
+				return ex.NewNil()
+			}
 
-			return macro
+			return ex.NewNumber(float64(len([]rune(args[0].String[:i]))))
 		},
-		Mod: &Mod{
-			Type: ModExec,
-			Exec: map[int]struct{}{},
+	},
+
+	"string-pad-left": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			return pad("string-pad-left", args, true)
 		},
 	},
 
-	"set!": {
+	"string-pad-right": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			return pad("string-pad-right", args, false)
+		},
+	},
+
+	"string-repeat": {
 		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
 			if len(args) != 2 {
-				return ex.NewFatal("set!: must be 2 arguments")
+				return ex.NewFatal("string-repeat: must be 2 arguments")
 			}
 
 			if args[0].Type != ex.Symbol {
-				return ex.NewFatal("set!: second argument is not symbol")
+				return ex.NewFatal("string-repeat: expected symbol, given " + args[0].ToString())
 			}
 
-			curEnv := ir.varsEnvironment
-			for curEnv != nil {
-				if _, ok := curEnv.CurSymbols[args[0].String]; ok {
-					curEnv.CurSymbols[args[0].String] = args[1]
-					return args[1]
-				}
-				curEnv = ir.varsEnvironment.Parent
+			n, fatal := natural("string-repeat", args[1])
+			if fatal != nil {
+				return fatal
 			}
 
-			return ex.NewFatal("set!: symbol '" + args[0].String + "' is not defined")
-		},
-		Mod: &Mod{
-			Type: ModExec,
-			Exec: map[int]struct{}{2: {}},
+			if n != 0 && len(args[0].String) > maxSymbolLength/n {
+				return ex.NewFatal("string-repeat: result is too long")
+			}
+
+			return ex.NewSymbol(strings.Repeat(args[0].String, n))
 		},
 	},
 
-	"lambda": {
+	"string-ref": {
 		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
-			if len(args) < 2 {
-				return ex.NewFatal("lambda: must be at less 2 arguments")
+			if len(args) != 2 {
+				return ex.NewFatal("string-ref: must be 2 arguments")
 			}
 
-			return ex.NewClosure(args[0], args[1:], ir.varsEnvironment)
-		},
-		Mod: &Mod{
-			Type: ModExec,
-			Exec: map[int]struct{}{},
-		},
-	},
+			if args[0].Type != ex.Symbol {
+				return ex.NewFatal("string-ref: expected symbol, given " + args[0].ToString())
+			}
 
-	"begin": {
-		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
-			if len(args) == 0 {
-				return ex.NewNil()
+			k, fatal := natural("string-ref", args[1])
+			if fatal != nil {
+				return fatal
 			}
-			return args[len(args)-1]
+
+			runes := []rune(args[0].String)
+			if k >= len(runes) {
+				return ex.NewFatal(fmt.Sprintf("string-ref: index %d is out of range of symbol of length %d", k, len(runes)))
+			}
+
+			return ex.NewSymbol(string(runes[k]))
 		},
 	},
 
-	"or": {
+	"char->string": {
 		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
-			for _, arg := range args {
-				if !arg.IsNil() {
-					return arg
-				}
+			if len(args) != 1 {
+				return ex.NewFatal("char->string: must be 1 argument")
 			}
 
-			return ex.NewNil()
-		},
-		Mod: &Mod{
-			Type: ModOr,
+			// characters are already symbols of one character, so only check it
+			if _, fatal := char("char->string", args[0]); fatal != nil {
+				return fatal
+			}
+
+			return ex.NewSymbol(args[0].String)
 		},
 	},
 
-	"and": {
+	"char-alphabetic?": {
 		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
-			if len(args) == 0 {
-				return ex.NewT()
-			}
+			return charPredicate("char-alphabetic?", args, unicode.IsLetter)
+		},
+	},
 
-			return args[len(args)-1]
+	"char-numeric?": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			return charPredicate("char-numeric?", args, unicode.IsDigit)
 		},
-		Mod: &Mod{
-			Type: ModAnd,
+	},
+
+	"char-whitespace?": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			return charPredicate("char-whitespace?", args, unicode.IsSpace)
 		},
 	},
 
-	"if": {
+	"symbol->number": {
 		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
-			if len(args) != 2 && len(args) != 3 {
-				return ex.NewFatal(fmt.Sprintf("if: expected 2 or 3 expressions, got %d", len(args)))
+			if len(args) != 1 {
+				return ex.NewFatal("symbol->number: must be 1 argument")
 			}
 
-			if !args[0].IsNil() {
-				return args[1]
+			if args[0].Type != ex.Symbol {
+				return ex.NewFatal("symbol->number: must be a symbol")
 			}
 
-			if len(args) == 2 {
-				return ex.NewNil()
+			lex := lexer.NewLexer(args[0].String)
+
+			tok, err := lex.NextToken()
+			if err != nil || tok.Tag != lexer.TagNumber {
+				return ex.NewFatal("string->number: incorrect string")
 			}
 
-			return args[2]
-		},
-		Mod: &Mod{
-			Type: ModIf,
+			tok2, err := lex.NextToken()
+			if err != nil || tok2.Tag != lexer.TagEOF {
+				return ex.NewFatal("string->number: incorrect string")
+			}
+
+			return ex.NewNumber(tok.Number)
 		},
 	},
 
-	">": {
+	"number->symbol": {
 		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
-			if len(args) != 2 {
-				return ex.NewFatal(fmt.Sprintf(">: expected 2 expressions, got %d", len(args)))
-			}
-
-			for _, arg := range args {
-				if arg.Type != ex.Number {
-					return ex.NewFatal(">: expected numbers")
-				}
+			if len(args) != 1 {
+				return ex.NewFatal("number->symbol: must be 1 argument")
 			}
 
-			if args[0].Number > args[1].Number {
-				return ex.NewT()
+			if args[0].Type != ex.Number {
+				return ex.NewFatal("number->symbol: must be a number")
 			}
 
-			return ex.NewNil()
+			return ex.NewSymbol(strconv.FormatFloat(args[0].Number, 'f', -1, 64))
 		},
 	},
 
-	"<": {
+	"integer->bits": {
 		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
 			if len(args) != 2 {
-				return ex.NewFatal(fmt.Sprintf("<: expected 2 expressions, got %d", len(args)))
+				return ex.NewFatal("integer->bits: must be 2 arguments")
 			}
 
-			for _, arg := range args {
-				if arg.Type != ex.Number {
-					return ex.NewFatal("<: expected numbers")
-				}
+			n, fatal := integer("integer->bits", args[0])
+			if fatal != nil {
+				return fatal
 			}
 
-			if args[0].Number < args[1].Number {
-				return ex.NewT()
+			width, fatal := natural("integer->bits", args[1])
+			if fatal != nil {
+				return fatal
 			}
 
-			return ex.NewNil()
-		},
-	},
-
-	"=": {
-		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
-			if len(args) < 2 {
-				return ex.NewFatal(fmt.Sprintf("=: expected at less 2 expressions, got %d", len(args)))
+			if width == 0 || width > 64 {
+				return ex.NewFatal("integer->bits: width must be from 1 to 64 bits, given " + args[1].ToString())
+			}
+
+			// negative numbers are written in two's complement
+			if n >= 0 && width < 64 && n>>uint(width) != 0 || n < 0 && n < -1<<uint(width-1) {
+				return ex.NewFatal(fmt.Sprintf("integer->bits: %d doesn't fit in %d bits", n, width))
 			}
 
-			cur := args[0]
-			for _, arg := range args[1:] {
-				if !cur.Equal(arg) {
-					return ex.NewNil()
-				}
+			bits := strconv.FormatUint(uint64(n), 2)
+			if len(bits) > width {
+				bits = bits[len(bits)-width:]
 			}
 
-			return ex.NewT()
+			return ex.NewSymbol(strings.Repeat("0", width-len(bits)) + bits)
 		},
 	},
 
-	"not": {
+	"random": {
 		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
-			if len(args) != 1 {
-				return ex.NewFatal("not: must be 1 argument")
+			if len(args) > 1 {
+				return ex.NewFatal("random: expected 0 or 1 arguments")
 			}
 
-			if args[0].IsNil() {
-				return ex.NewT()
+			if len(args) == 0 {
+				return ex.NewNumber(ir.random.Float64())
 			}
 
-			return ex.NewNil()
+			n, fatal := natural("random", args[0])
+			if fatal != nil {
+				return fatal
+			}
+
+			if n == 0 {
+				return ex.NewFatal("random: bound must be positive")
+			}
+
+			return ex.NewNumber(float64(ir.random.Int63n(int64(n))))
 		},
 	},
 
-	"pair?": {
+	"random-seed": {
 		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
 			if len(args) != 1 {
-				return ex.NewFatal("pair?: must be 1 argument")
+				return ex.NewFatal("random-seed: must be 1 argument")
 			}
 
-			if args[0].Type == ex.Pair {
-				return ex.NewT()
+			seed, fatal := integer("random-seed", args[0])
+			if fatal != nil {
+				return fatal
 			}
 
+			ir.random = rand.New(rand.NewSource(seed))
 			return ex.NewNil()
 		},
 	},
 
-	"number?": {
+	"current-time": {
 		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
-			if len(args) != 1 {
-				return ex.NewFatal("number?: must be 1 argument")
+			if len(args) != 0 {
+				return ex.NewFatal("current-time: expected zero expressions")
 			}
 
-			if args[0].Type == ex.Number {
-				return ex.NewT()
+			return ex.NewNumber(float64(time.Now().Unix()))
+		},
+	},
+
+	"runtime": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 0 {
+				return ex.NewFatal("runtime: expected zero expressions")
 			}
 
-			return ex.NewNil()
+			// time.Since uses monotonic clock, so result isn't affected by changes of system time
+			return ex.NewNumber(float64(time.Since(ir.start) / time.Microsecond))
 		},
 	},
 
-	"symbol?": {
+	"round-to": {
 		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
-			if len(args) != 1 {
-				return ex.NewFatal("symbol?: must be 1 argument")
+			if len(args) != 2 {
+				return ex.NewFatal("round-to: must be 2 arguments")
 			}
 
-			if args[0].Type == ex.Symbol {
-				return ex.NewT()
+			if args[0].Type != ex.Number {
+				return ex.NewFatal("round-to: first argument must be a number")
 			}
 
-			return ex.NewNil()
+			digits, fatal := natural("round-to", args[1])
+			if fatal != nil {
+				return fatal
+			}
+
+			// numbers which scaled values overflow or have no fractional part are already rounded to the digits
+			scale := math.Pow(10, float64(digits))
+			scaled := args[0].Number * scale
+			if math.IsInf(scale, 0) || math.IsInf(scaled, 0) || math.Abs(scaled) >= 1<<53 {
+				return args[0]
+			}
+
+			return ex.NewNumber(math.Round(scaled) / scale)
 		},
 	},
 
-	"len": {
+	"sin": {
 		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
-			if len(args) != 1 {
-				return ex.NewFatal("len: must be 1 argument")
-			}
-
-			if args[0].Type != ex.Symbol {
-				return ex.NewFatal("len: must be a symbol")
-			}
+			return mathFunc("sin", args, math.Sin, nil)
+		},
+	},
 
-			return ex.NewNumber(float64(len([]rune(args[0].String))))
+	"cos": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			return mathFunc("cos", args, math.Cos, nil)
 		},
 	},
 
-	"symbol->number": {
+	"tan": {
 		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
-			if len(args) != 1 {
-				return ex.NewFatal("symbol->number: must be 1 argument")
-			}
+			return mathFunc("tan", args, math.Tan, nil)
+		},
+	},
 
-			if args[0].Type != ex.Symbol {
-				return ex.NewFatal("symbol->number: must be a symbol")
-			}
+	"asin": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			return mathFunc("asin", args, math.Asin, func(x float64) bool { return -1 <= x && x <= 1 })
+		},
+	},
 
-			lex := lexer.NewLexer(args[0].String)
+	"acos": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			return mathFunc("acos", args, math.Acos, func(x float64) bool { return -1 <= x && x <= 1 })
+		},
+	},
 
-			tok, err := lex.NextToken()
-			if err != nil || tok.Tag != lexer.TagNumber {
-				return ex.NewFatal("string->number: incorrect string")
+	"atan": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 2 {
+				return mathFunc("atan", args, math.Atan, nil)
 			}
 
-			tok2, err := lex.NextToken()
-			if err != nil || tok2.Tag != lexer.TagEOF {
-				return ex.NewFatal("string->number: incorrect string")
+			yx, fatal := numbers("atan", args)
+			if fatal != nil {
+				return fatal
 			}
 
-			return ex.NewNumber(tok.Number)
+			return ex.NewNumber(math.Atan2(yx[0], yx[1]))
 		},
 	},
 
-	"number->symbol": {
+	"exp": {
 		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
-			if len(args) != 1 {
-				return ex.NewFatal("number->symbol: must be 1 argument")
+			return mathFunc("exp", args, math.Exp, nil)
+		},
+	},
+
+	"log": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			positive := func(x float64) bool { return x > 0 }
+			if len(args) != 2 {
+				return mathFunc("log", args, math.Log, positive)
 			}
 
-			if args[0].Type != ex.Number {
-				return ex.NewFatal("number->symbol: must be a number")
+			xb, fatal := numbers("log", args)
+			if fatal != nil {
+				return fatal
 			}
 
-			return ex.NewSymbol(strconv.FormatFloat(args[0].Number, 'f', -1, 64))
+			if !positive(xb[0]) || !positive(xb[1]) || xb[1] == 1 {
+				return ex.NewFatal("log: arguments are out of domain: " + args[0].ToString() + " " + args[1].ToString())
+			}
+
+			return ex.NewNumber(math.Log(xb[0]) / math.Log(xb[1]))
 		},
 	},
 
@@ -476,7 +3806,7 @@ var functions = map[string]Func{
 				res := 0.0
 				for _, arg := range args {
 					if arg.Type != ex.Number {
-						return ex.NewFatal("+: expected numbers, given " + arg.ToString())
+						return ex.NewFatal("+: expected numbers, given " + given(arg))
 					}
 					res += arg.Number
 				}
@@ -588,6 +3918,111 @@ var functions = map[string]Func{
 		},
 	},
 
+	"floor/": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			a, b, fatal := divisionArgs("floor/", args)
+			if fatal != nil {
+				return fatal
+			}
+
+			q := math.Floor(a / b)
+			return ex.NewList(ex.NewNumber(q), ex.NewNumber(a-b*q))
+		},
+	},
+
+	"truncate/": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			a, b, fatal := divisionArgs("truncate/", args)
+			if fatal != nil {
+				return fatal
+			}
+
+			return ex.NewList(ex.NewNumber(math.Trunc(a/b)), ex.NewNumber(math.Mod(a, b)))
+		},
+	},
+
+	"exact-integer-sqrt": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("exact-integer-sqrt: must be 1 argument")
+			}
+
+			n, fatal := natural("exact-integer-sqrt", args[0])
+			if fatal != nil {
+				return fatal
+			}
+
+			// square root of float is corrected for big numbers
+			root := int(math.Sqrt(float64(n)))
+			for root*root > n {
+				root--
+			}
+			for (root+1)*(root+1) <= n {
+				root++
+			}
+
+			return ex.NewList(ex.NewNumber(float64(root)), ex.NewNumber(float64(n-root*root)))
+		},
+	},
+
+	"bitwise-and": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			return bitwise("bitwise-and", args, -1, func(a, b int64) int64 { return a & b })
+		},
+	},
+
+	"bitwise-or": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			return bitwise("bitwise-or", args, 0, func(a, b int64) int64 { return a | b })
+		},
+	},
+
+	"bitwise-xor": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			return bitwise("bitwise-xor", args, 0, func(a, b int64) int64 { return a ^ b })
+		},
+	},
+
+	"bitwise-not": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("bitwise-not: must be 1 argument")
+			}
+
+			n, fatal := integer("bitwise-not", args[0])
+			if fatal != nil {
+				return fatal
+			}
+
+			return ex.NewNumber(float64(^n))
+		},
+	},
+
+	"arithmetic-shift": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 2 {
+				return ex.NewFatal("arithmetic-shift: must be 2 arguments")
+			}
+
+			n, fatal := integer("arithmetic-shift", args[0])
+			if fatal != nil {
+				return fatal
+			}
+
+			count, fatal := integer("arithmetic-shift", args[1])
+			if fatal != nil {
+				return fatal
+			}
+
+			// negative count shifts right keeping sign
+			if count < 0 {
+				return ex.NewNumber(float64(n >> uint64(-count)))
+			}
+
+			return ex.NewNumber(float64(n << uint64(count)))
+		},
+	},
+
 	"write": {
 		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
 			if len(args) != 1 {
@@ -603,6 +4038,119 @@ var functions = map[string]Func{
 		},
 	},
 
+	"pp": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("pp: expected one expression")
+			}
+
+			_, err := fmt.Fprintln(ir.stdout, args[0].PrettyString(ppWidth))
+			if err != nil {
+				return ex.NewFatal(err.Error())
+			}
+
+			return args[0]
+		},
+	},
+
+	"with-output-to-string": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("with-output-to-string: expected one expression")
+			}
+
+			if fatal := callable("with-output-to-string", args[0]); fatal != nil {
+				return fatal
+			}
+
+			return begin(ex.NewList(ex.NewFunction("%push-output")),
+				ex.NewList(ex.NewFunction("%unwind"), ex.NewFunction("%pop-output"), args[0].ToList()))
+		},
+		Eval: true,
+	},
+
+	"%push-output": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			buf := bytes.NewBufferString("")
+			ir.outputs = append(ir.outputs, capturedOutput{prev: ir.stdout, buf: buf})
+			ir.stdout = buf
+
+			return ex.NewNil()
+		},
+	},
+
+	"%pop-output": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(ir.outputs) == 0 {
+				return ex.NewFatal("%pop-output: output wasn't captured")
+			}
+
+			last := ir.outputs[len(ir.outputs)-1]
+			ir.outputs = ir.outputs[:len(ir.outputs)-1]
+			ir.stdout = last.prev
+
+			return ex.NewSymbol(last.buf.String())
+		},
+	},
+
+	"format": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) == 0 || args[0].Type != ex.Symbol {
+				return ex.NewFatal("format: first argument must be a symbol")
+			}
+
+			var res strings.Builder
+			runes := []rune(args[0].String)
+			used := 1
+			for i := 0; i < len(runes); i++ {
+				if runes[i] != '~' {
+					res.WriteRune(runes[i])
+					continue
+				}
+
+				if i++; i == len(runes) {
+					return ex.NewFatal("format: unexpected end of format string")
+				}
+
+				switch runes[i] {
+				case '%':
+					res.WriteRune('\n')
+					continue
+				case '~':
+					res.WriteRune('~')
+					continue
+				}
+
+				if used == len(args) {
+					return ex.NewFatal("format: not enough arguments")
+				}
+
+				arg := args[used]
+				used++
+
+				switch runes[i] {
+				case 'a':
+					res.WriteString(arg.ToString())
+				case 's':
+					res.WriteString(readable(arg))
+				case 'd':
+					if arg.Type != ex.Number {
+						return ex.NewFatal("format: ~d expects number, given " + arg.ToString())
+					}
+					res.WriteString(arg.ToString())
+				default:
+					return ex.NewFatal("format: unknown directive ~" + string(runes[i]))
+				}
+			}
+
+			if used != len(args) {
+				return ex.NewFatal("format: too many arguments")
+			}
+
+			return ex.NewSymbol(res.String())
+		},
+	},
+
 	"read": {
 		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
 			if len(args) != 0 {
@@ -612,16 +4160,22 @@ var functions = map[string]Func{
 			var expr *ex.Expr
 			var exprStr string
 			for {
-				str, err := bufio.NewReader(ir.stdin).ReadString('\n')
-				if err != nil && err != io.EOF {
-					return ex.NewFatal("read: " + err.Error())
+				str, readErr := ir.stdin.ReadString('\n')
+				if readErr != nil && readErr != io.EOF {
+					return ex.NewFatal("read: " + readErr.Error())
+				}
+
+				if readErr == io.EOF && exprStr == "" && str == "" {
+					return ex.NewEOF()
 				}
 
 				exprStr += str
 
+				var err error
 				expr, err = parser.NewParser(exprStr).Parse()
 				if err != nil {
-					if pErr, ok := err.(*parser.ParseError); ok && pErr.Got == lexer.TagEOF {
+					// unfinished expression is continued on the next line unless input is ended
+					if pErr, ok := err.(*parser.ParseError); ok && pErr.Got == lexer.TagEOF && readErr != io.EOF {
 						continue
 					}
 					return ex.NewFatal("read: " + err.Error())
@@ -634,29 +4188,92 @@ var functions = map[string]Func{
 		},
 	},
 
+	"read-line": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 0 {
+				return ex.NewFatal("read-line: expected zero expressions")
+			}
+
+			str, err := ir.stdin.ReadString('\n')
+			if err != nil && err != io.EOF {
+				return ex.NewFatal("read-line: " + err.Error())
+			}
+
+			if err == io.EOF && str == "" {
+				return ex.NewEOF()
+			}
+
+			return ex.NewSymbol(strings.TrimSuffix(strings.TrimSuffix(str, "\n"), "\r"))
+		},
+	},
+
+	"read-char": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 0 {
+				return ex.NewFatal("read-char: expected zero expressions")
+			}
+
+			return readChar("read-char", ir, false)
+		},
+	},
+
+	"peek-char": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 0 {
+				return ex.NewFatal("peek-char: expected zero expressions")
+			}
+
+			return readChar("peek-char", ir, true)
+		},
+	},
+
+	"eof-object?": {
+		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
+			if len(args) != 1 {
+				return ex.NewFatal("eof-object?: must be 1 argument")
+			}
+
+			if args[0].Type == ex.EOF {
+				return ex.NewT()
+			}
+
+			return ex.NewNil()
+		},
+	},
+
 	"load": {
 		F: func(ir *interpreter, args []*ex.Expr) *ex.Expr {
 			if len(args) != 1 {
-				return ex.NewFatal("load: expected one expression")
+				return ex.NewFatal("load: must be 1 argument")
 			}
 
 			if args[0].Type != ex.Symbol {
-				return ex.NewFatal("load: expected zero expressions")
+				return ex.NewFatal("load: expected symbol, given " + args[0].ToString())
+			}
+
+			if ir.options.NoFileAccess {
+				return ex.NewFatal("load: access to files is forbidden")
 			}
 
 			file, err := ioutil.ReadFile(args[0].String)
-			if err != nil {
+			if os.IsNotExist(err) {
+				return ex.NewFatal("load: file '" + args[0].String + "' does not exist")
+			} else if err != nil {
 				return ex.NewFatal("load: " + err.Error())
 			}
 
-			str := string(file)
-
-			expr, err := parser.NewParser(str).Parse()
+			exprs, err := parser.NewParser(string(file)).Parse()
 			if err != nil {
 				return ex.NewFatal("load: " + err.Error())
 			}
 
-			return expr
+			// expressions are calculated in the root scope, the caller's scope is restored when the call ends
+			if ir.varsEnvironment != ir.globals {
+				ir.setNewVars(ir.globals)
+			}
+
+			return ex.NewFunction("begin").Cons(exprs)
 		},
+		Eval: true,
 	},
 }
@@ -1,13 +1,17 @@
 package interpreter
 
 import (
+	"bufio"
 	"bytes"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
+	"math/rand"
 	"os"
 	"strings"
+	"time"
 
 	ex "github.com/batrSens/LispXS/expressions"
 	"github.com/batrSens/LispXS/parser"
@@ -41,6 +45,10 @@ func LoadLibrary(path string) (*Library, error) {
 	res := interpreter.run()
 
 	if res.Type == ex.Fatal {
+		if backtrace := res.Backtrace(); len(backtrace) > 0 {
+			return nil, errors.New(res.String + " (in " + strings.Join(backtrace, ", in ") + ")")
+		}
+
 		return nil, errors.New(res.String)
 	}
 
@@ -59,7 +67,102 @@ func (lib *Library) Call(symbol string, args ...interface{}) (*ex.Expr, error) {
 	return res, nil
 }
 
+// Options changes behaviour of interpreter. Zero value means default behaviour
+type Options struct {
+	// StrictArithmetic makes non-finite (NaN or infinite) results of functions an error
+	StrictArithmetic bool
+	// CompileClosures calculates calls of closures by bodies compiled to trees of Go closures when the bodies use only
+	// quote, if, begin, and, or, pure functions and calls of such closures. Other calls are calculated as usual
+	CompileClosures bool
+	// UnboundAsNil makes references to undefined symbols return nil instead of an error
+	UnboundAsNil bool
+	// FalsyZeroAndEmpty makes 0 and empty symbol false in conditions like nil
+	FalsyZeroAndEmpty bool
+	// FoldConstants calculates arithmetic of number literals (e.g. (+ 2 3)) once before execution. Functions which
+	// names are used by program not only in calls, arguments of macros and not calculated arguments of special forms
+	// aren't folded
+	FoldConstants bool
+	// NoFileAccess forbids functions to read files (load)
+	NoFileAccess bool
+}
+
+// Execute calculates program in the library's root scope
+func (lib *Library) Execute(program string) (*ex.Expr, error) {
+	exprs, err := parser.NewParser(program).Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	lib.interpreter.control = exprs
+	res := lib.interpreter.run()
+
+	return res, nil
+}
+
+// Snapshot is a saved state of symbols of the library's root scope
+type Snapshot struct {
+	symbols map[string]*ex.Expr
+}
+
+// Snapshot saves symbols of the library's root scope. Following definitions, redefinitions and removals of root symbols
+// and changes of their pairs, vectors and records don't change the snapshot
+func (lib *Library) Snapshot() *Snapshot {
+	snapshot := &Snapshot{symbols: map[string]*ex.Expr{}}
+	copies := map[*ex.Expr]*ex.Expr{}
+	for name, expr := range lib.interpreter.root().CurSymbols {
+		snapshot.symbols[name] = copyData(expr, copies)
+	}
+
+	return snapshot
+}
+
+// Restore returns symbols of the library's root scope to the saved state. Closures defined before the snapshot keep
+// working because the scope itself isn't replaced. The snapshot can be restored several times
+func (lib *Library) Restore(snapshot *Snapshot) {
+	symbols := lib.interpreter.root().CurSymbols
+	for name := range symbols {
+		delete(symbols, name)
+	}
+
+	copies := map[*ex.Expr]*ex.Expr{}
+	for name, expr := range snapshot.symbols {
+		symbols[name] = copyData(expr, copies)
+	}
+}
+
+// copyData returns deep copy of mutable data: pairs, vectors and records. Shared and cyclic structure is kept, copies
+// maps already copied data to their copies. Other expressions are returned as is
+func copyData(expr *ex.Expr, copies map[*ex.Expr]*ex.Expr) *ex.Expr {
+	if cp, ok := copies[expr]; ok {
+		return cp
+	}
+
+	switch expr.Type {
+	case ex.Pair:
+		cp := ex.NewNil().Cons(ex.NewNil())
+		copies[expr] = cp
+		cp.SetCar(copyData(expr.Car(), copies))
+		cp.SetCdr(copyData(expr.Cdr(), copies))
+		return cp
+	case ex.Vector, ex.Record:
+		cp := *expr
+		cp.Vector = make([]*ex.Expr, len(expr.Vector))
+		copies[expr] = &cp
+		for i, elem := range expr.Vector {
+			cp.Vector[i] = copyData(elem, copies)
+		}
+
+		return &cp
+	}
+
+	return expr
+}
+
 func Execute(program string) (*Output, error) {
+	return ExecuteWithOptions(program, Options{})
+}
+
+func ExecuteWithOptions(program string, options Options) (*Output, error) {
 	prs := parser.NewParser(program)
 	exprs, err := prs.Parse()
 	if err != nil {
@@ -68,7 +171,12 @@ func Execute(program string) (*Output, error) {
 
 	outstr, errstr := bytes.NewBufferString(""), bytes.NewBufferString("")
 
-	res := newInterpreter(exprs, outstr, errstr, os.Stdin).run()
+	interpreter := newInterpreter(exprs, outstr, errstr, os.Stdin)
+	interpreter.options = options
+	if options.FoldConstants {
+		interpreter.control = interpreter.foldProgram(interpreter.control)
+	}
+	res := interpreter.run()
 
 	return &Output{
 		Stdout: outstr.String(),
@@ -138,6 +246,161 @@ func newList(root bool, args []interface{}) (*ex.Expr, error) {
 	return list, nil
 }
 
+// foldable are pure functions which calls with number literals are calculated by constant folding
+var foldable = map[string]struct{}{
+	"+": {}, "-": {}, "*": {}, "/": {}, "round-to": {},
+	"sin": {}, "cos": {}, "tan": {}, "asin": {}, "acos": {}, "atan": {}, "exp": {}, "log": {},
+	"bitwise-and": {}, "bitwise-or": {}, "bitwise-xor": {}, "bitwise-not": {}, "arithmetic-shift": {},
+}
+
+// constantFolder folds constants of program. Rebound are foldable functions which names are used by program not only as
+// heads of calls (e.g. (define + -) or (lambda (+) 1)), so they may be redefined. Macros are names of macros defined by
+// program, their arguments are code that is not calculated as it is
+type constantFolder struct {
+	ir      *interpreter
+	rebound map[string]struct{}
+	macros  map[string]struct{}
+}
+
+// foldProgram folds constants of every expression of the program
+func (ir *interpreter) foldProgram(program *ex.Expr) *ex.Expr {
+	exprs, ok := program.ToSlice()
+	if !ok {
+		return program
+	}
+
+	cf := &constantFolder{ir: ir, rebound: map[string]struct{}{}, macros: map[string]struct{}{}}
+	for _, expr := range exprs {
+		cf.scan(expr)
+	}
+
+	for i, expr := range exprs {
+		exprs[i] = cf.fold(expr)
+	}
+
+	return ex.NewList(exprs...)
+}
+
+// scan finds rebound functions and macros of the expression
+func (cf *constantFolder) scan(expr *ex.Expr) {
+	if expr.Type == ex.Symbol {
+		if _, ok := foldable[expr.String]; ok {
+			cf.rebound[expr.String] = struct{}{}
+		}
+	}
+
+	elems, ok := expr.ToSlice()
+	if expr.Type != ex.Pair || !ok {
+		return
+	}
+
+	if len(elems) > 1 && elems[0].Type == ex.Symbol && elems[0].String == "defmacro" && elems[1].Type == ex.Symbol {
+		cf.macros[elems[1].String] = struct{}{}
+	}
+
+	for i, elem := range elems {
+		// head of call with arguments is a use of function
+		if i == 0 && elem.Type == ex.Symbol && len(elems) > 1 {
+			continue
+		}
+
+		cf.scan(elem)
+	}
+}
+
+// fold replaces calls of foldable functions with number literals by their results. Quoted data, arguments of macros,
+// not calculated arguments of special forms and expressions marked for macros are left untouched, as well as calls
+// which result is an error or non-finite number
+func (cf *constantFolder) fold(expr *ex.Expr) *ex.Expr {
+	if expr.Type != ex.Pair || expr.CalculatedForMacro {
+		return expr
+	}
+
+	elems, ok := expr.ToSlice()
+	if !ok {
+		return expr
+	}
+
+	head := elems[0]
+	if _, ok := cf.macros[head.String]; ok && head.Type == ex.Symbol {
+		return expr
+	}
+
+	f, ok := functions[head.String]
+	switch {
+	case head.Type != ex.Symbol && head.Type != ex.Function || !ok || f.Mod == nil || f.Mod.Type != ModExec:
+		for i, elem := range elems {
+			elems[i] = cf.fold(elem)
+		}
+
+	// body of lambda is calculated by its calls, unless arguments shadow foldable functions
+	case head.String == "lambda":
+		if len(elems) > 2 && !cf.shadows(elems[1]) {
+			for i := 2; i < len(elems); i++ {
+				elems[i] = cf.fold(elems[i])
+			}
+		}
+
+	default:
+		for i := 1; i < len(elems); i++ {
+			if _, ok := f.Mod.Exec[i]; ok {
+				elems[i] = cf.fold(elems[i])
+			}
+		}
+	}
+
+	if cf.constantCall(elems) {
+		res := functions[head.String].F(cf.ir, elems[1:])
+		if res.Type == ex.Number && !math.IsNaN(res.Number) && !math.IsInf(res.Number, 0) {
+			return res
+		}
+	}
+
+	return ex.NewList(elems...)
+}
+
+// constantCall reports whether list is a call of foldable function that isn't rebound with at least one argument and
+// all arguments are number literals
+func (cf *constantFolder) constantCall(elems []*ex.Expr) bool {
+	head := elems[0]
+	if _, ok := foldable[head.String]; !ok || head.Type != ex.Symbol || len(elems) < 2 {
+		return false
+	}
+
+	if _, ok := cf.rebound[head.String]; ok {
+		return false
+	}
+
+	for _, elem := range elems[1:] {
+		if elem.Type != ex.Number {
+			return false
+		}
+	}
+
+	return true
+}
+
+// shadows reports whether arguments of lambda contain names of foldable functions
+func (cf *constantFolder) shadows(params *ex.Expr) bool {
+	if params.Type == ex.Symbol {
+		_, ok := foldable[params.String]
+		return ok
+	}
+
+	elems, ok := params.ToSlice()
+	if params.Type != ex.Pair || !ok {
+		return false
+	}
+
+	for _, elem := range elems {
+		if cf.shadows(elem) {
+			return true
+		}
+	}
+
+	return false
+}
+
 type stackExpr []*ex.Expr
 
 func (se *stackExpr) Push(expr *ex.Expr) {
@@ -172,6 +435,9 @@ type call struct {
 	argsNum         int
 	mod             *Mod
 	varsEnvironment *ex.Vars
+
+	// name is a name of the closure that is evaluated by the call, it is empty for calls of functions
+	name string
 }
 
 type stackCall []call
@@ -208,6 +474,12 @@ func (sc *stackCall) SetVars(vars *ex.Vars) {
 	(*sc)[len(*sc)-1] = last
 }
 
+func (sc *stackCall) SetName(name string) {
+	last := (*sc)[len(*sc)-1]
+	last.name = name
+	(*sc)[len(*sc)-1] = last
+}
+
 func (sc *stackCall) SetMod(mod *Mod) {
 	last := (*sc)[len(*sc)-1]
 	last.mod = mod
@@ -224,7 +496,81 @@ type interpreter struct {
 	varsEnvironment *ex.Vars
 
 	stdout, stderr io.Writer
-	stdin          io.Reader
+	// stdin is buffered once, so data read ahead by one call of input function isn't lost for the following calls
+	stdin *bufio.Reader
+
+	outputs []capturedOutput
+	options Options
+
+	// random is a source of random numbers that can be seeded by program
+	random *rand.Rand
+	start  time.Time
+
+	// traced maps tracing closures to the traced functions, traceDepth is a depth of nested traced calls
+	traced     map[*ex.Expr]*ex.Expr
+	traceDepth int
+
+	// compiled are compiled bodies of closures, compiledDepth is a depth of nested calls of compiled closures.
+	// tailClosure and tailArgs are the last call in tail position of compiled closure
+	compiled      map[compiledKey]*compiledBody
+	compiledDepth int
+	tailClosure   *ex.Expr
+	tailArgs      []*ex.Expr
+
+	// globals is the root scope. shadowed contains names which were bound in local scopes at least once, the rest names
+	// are resolved in the root scope without walking the chain of scopes
+	globals  *ex.Vars
+	shadowed map[string]struct{}
+
+	// modules are scopes of modules by their names
+	modules map[string]*ex.Vars
+	// features are names of loaded features, files of them aren't loaded again by require
+	features map[string]struct{}
+
+	// parameterizations is a stack of values of parameters saved by parameterize, they are restored when its body ends
+	parameterizations [][]parameterization
+
+	// continuations are tags of continuations which call/cc hasn't returned yet, continuationsNum is a number of created
+	// continuations
+	continuations    map[string]struct{}
+	continuationsNum int
+
+	// generators is a stack of running generators, the last one gets values of yield. Suspended are stacks of
+	// generators saved by yield until the next call of generator-next
+	generators []generatorRun
+	suspended  map[*ex.Expr]suspendedGenerator
+
+	// tests are registered by deftest in order of registration
+	tests []registeredTest
+}
+
+type registeredTest struct {
+	name  string
+	thunk *ex.Expr
+}
+
+// generatorRun is a calculation of the generator's closure started or resumed by generator-next. Calls and data are
+// sizes of stacks and vars is a scope of the caller of generator-next, yield returns to it
+type generatorRun struct {
+	generator   *ex.Expr
+	calls, data int
+	vars        *ex.Vars
+}
+
+// suspendedGenerator is a part of stacks and a scope of generator's calculation saved by yield
+type suspendedGenerator struct {
+	calls []call
+	data  []*ex.Expr
+	vars  *ex.Vars
+}
+
+type parameterization struct {
+	param, value *ex.Expr
+}
+
+type capturedOutput struct {
+	prev io.Writer
+	buf  *bytes.Buffer
 }
 
 func loadPrelude() *ex.Expr {
@@ -247,11 +593,19 @@ func newInterpreter(program *ex.Expr, stdout, stderr io.Writer, stdin io.Reader)
 	vars := ex.NewRootVars()
 
 	for f := range functions {
+		// internal functions (with '%' prefix) are called only by code generated by other functions and aren't
+		// accessible from programs
+		if strings.HasPrefix(f, "%") {
+			continue
+		}
+
 		vars.CurSymbols[f] = ex.NewFunction(f)
 	}
 
-	vars.CurSymbols["T"] = ex.NewSymbol("T")
+	vars.CurSymbols["T"] = ex.NewT()
 	vars.CurSymbols["nil"] = ex.NewNil()
+	vars.CurSymbols["pi"] = ex.NewNumber(math.Pi)
+	vars.CurSymbols["e"] = ex.NewNumber(math.E)
 
 	if prelude := loadPrelude(); prelude != nil {
 		program = prelude.Cons(program)
@@ -262,7 +616,17 @@ func newInterpreter(program *ex.Expr, stdout, stderr io.Writer, stdin io.Reader)
 		varsEnvironment: vars,
 		stderr:          stderr,
 		stdout:          stdout,
-		stdin:           stdin,
+		stdin:           bufio.NewReader(stdin),
+		random:          rand.New(rand.NewSource(time.Now().UnixNano())),
+		start:           time.Now(),
+		traced:          map[*ex.Expr]*ex.Expr{},
+		compiled:        map[compiledKey]*compiledBody{},
+		globals:         vars,
+		shadowed:        map[string]struct{}{},
+		modules:         map[string]*ex.Vars{},
+		features:        map[string]struct{}{},
+		continuations:   map[string]struct{}{},
+		suspended:       map[*ex.Expr]suspendedGenerator{},
 	}
 }
 
@@ -293,13 +657,17 @@ func (ir *interpreter) run() *ex.Expr {
 			}
 
 			switch curExpr.Type {
-			case ex.Number, ex.Nil, ex.Fatal, ex.Function, ex.Closure, ex.Macro:
+			case ex.Number, ex.Nil, ex.Fatal, ex.Function, ex.Closure, ex.Macro, ex.Promise, ex.Vector, ex.EOF, ex.Environment, ex.Parameter, ex.Generator, ex.Record:
 				ir.dataStack.Push(curExpr)
 			case ex.Symbol:
 				expr := ir.resolveSymbol(curExpr)
 				ir.dataStack.Push(expr)
 			case ex.Pair:
-				ir.pushLastCall()
+				if ir.isTailCall() {
+					ir.tailCall()
+				} else {
+					ir.pushLastCall()
+				}
 			default:
 				panic(fmt.Sprint("unexpected symbol type ", curExpr.Type))
 			}
@@ -312,7 +680,7 @@ func (ir *interpreter) run() *ex.Expr {
 			case ex.Function:
 				ir.execFunc(f, args)
 
-				if f.String == "eval" {
+				if functions[f.String].Eval && ir.dataStack.Last().Type != ex.Fatal {
 					ir.control = ir.dataStack.Pop()
 					ir.argsNum = 0
 					ir.mod = nil
@@ -331,6 +699,10 @@ func (ir *interpreter) run() *ex.Expr {
 					return ir.dataStack.Pop()
 				}
 
+				if res := ir.dataStack.Last(); res.Type == ex.Fatal {
+					ir.addBacktrace(res)
+				}
+
 				ir.popLastCallAndCheckMacro()
 
 			case ex.Closure:
@@ -339,6 +711,14 @@ func (ir *interpreter) run() *ex.Expr {
 			case ex.Macro:
 				ir.callMacro(f, args)
 
+			case ex.Parameter:
+				if len(args) != 0 {
+					ir.dataStack.Push(ex.NewFatal("parameter: expected zero arguments"))
+				} else {
+					ir.dataStack.Push(f.ParameterValue())
+				}
+				ir.popLastCallAndCheckMacro()
+
 			default:
 				ir.dataStack.Push(ex.NewFatal("call: " + f.DebugString() + " is not a function"))
 				ir.popLastCallAndCheckMacro()
@@ -350,6 +730,7 @@ func (ir *interpreter) run() *ex.Expr {
 func (ir *interpreter) fatalFall() *ex.Expr {
 	fatal := ir.dataStack.Pop()
 	var f *ex.Expr
+	var args []*ex.Expr
 
 	for i := 0; true; i++ {
 		if i > 0 {
@@ -363,12 +744,13 @@ func (ir *interpreter) fatalFall() *ex.Expr {
 				cur := ir.control.Cdr()
 				for !cur.IsNil() {
 					if cur.Type != ex.Pair || cur.Car().Car().Type != ex.Symbol ||
-						(!strings.HasPrefix(fatal.String, cur.Car().Car().String) && cur.Car().Car().String != "default") {
+						(!strings.HasPrefix(fatal.String, cur.Car().Car().String) &&
+							(cur.Car().Car().String != "default" || strings.HasPrefix(fatal.String, continuationTag))) {
 						cur = cur.Cdr()
 						continue
 					}
 
-					ir.varsEnvironment.CurSymbols["error_description"] = ex.NewSymbol(fatal.String)
+					ir.bind("error_description", ex.NewSymbol(fatal.String))
 
 					if cur.Car().Cdr().IsNil() {
 						ir.dataStack.Push(fatal.Res)
@@ -384,6 +766,20 @@ func (ir *interpreter) fatalFall() *ex.Expr {
 
 			}
 
+			// cleanup of dynamic-wind is called before the error falls further
+			if f.Equal(ex.NewFunction("%unwind")) && ir.argsNum == 2 {
+				ir.control = begin(ex.NewList(args[0], ex.NewNil()), fatal)
+				ir.argsNum = 0
+				ir.mod = nil
+				return nil
+			}
+
+			// traced call is interrupted by the error, so it doesn't write its result
+			if f.Equal(ex.NewFunction("%trace-exit")) && ir.argsNum == 1 {
+				ir.traceDepth--
+			}
+
+			ir.addBacktrace(fatal)
 			ir.popLastCall()
 		}
 
@@ -391,7 +787,7 @@ func (ir *interpreter) fatalFall() *ex.Expr {
 		if ir.argsNum <= 0 {
 			fatal.AddTrace(ex.NewSymbol("none"), ir.argsNum)
 		} else {
-			f, _ = ir.popArgs()
+			f, args = ir.popArgs()
 			fatal.AddTrace(f, ir.argsNum)
 		}
 	}
@@ -399,6 +795,13 @@ func (ir *interpreter) fatalFall() *ex.Expr {
 	panic("unexpected")
 }
 
+// addBacktrace adds name of the closure evaluated by the last call to backtrace of the fatal
+func (ir *interpreter) addBacktrace(fatal *ex.Expr) {
+	if name := ir.callStack.Last().name; name != "" {
+		fatal.AddBacktrace(name)
+	}
+}
+
 func (ir *interpreter) modLoad() {
 	switch ir.dataStack.Last().Type {
 	case ex.Function:
@@ -416,16 +819,93 @@ func (ir *interpreter) modLoad() {
 }
 
 func (ir *interpreter) resolveSymbol(symbol *ex.Expr) *ex.Expr {
+	return ir.resolveSymbolIn(ir.varsEnvironment, symbol)
+}
+
+// resolveSymbolIn returns value of the symbol in the scope
+func (ir *interpreter) resolveSymbolIn(vars *ex.Vars, symbol *ex.Expr) *ex.Expr {
+	if expr, ok := ir.lookupIn(vars, symbol.String); ok {
+		return expr
+	}
+
+	if ir.options.UnboundAsNil {
+		return ex.NewNil()
+	}
+
+	return ex.NewFatal(fmt.Sprintf("call: symbol '%s' is not defined", symbol.String))
+}
+
+// isFalse reports whether expression is false in conditions
+func (ir *interpreter) isFalse(expr *ex.Expr) bool {
+	if ir.options.FalsyZeroAndEmpty && (expr.Type == ex.Number && expr.Number == 0 || expr.Type == ex.Symbol && expr.String == "") {
+		return true
+	}
+
+	return expr.IsNil()
+}
+
+func (ir *interpreter) root() *ex.Vars {
+	return ir.globals
+}
+
+// bind defines the symbol in the current scope
+func (ir *interpreter) bind(name string, expr *ex.Expr) {
+	ir.varsEnvironment.Set(name, expr)
+	if ir.varsEnvironment != ir.globals {
+		ir.shadowed[name] = struct{}{}
+	}
+}
+
+// shadow marks arguments of closure's call as shadowed
+func (ir *interpreter) shadow(vars *ex.Vars) {
+	for _, name := range vars.Params() {
+		if _, ok := ir.shadowed[name]; !ok {
+			ir.shadowed[name] = struct{}{}
+		}
+	}
+}
+
+// lookup returns value of the symbol in the nearest scope that contains it
+func (ir *interpreter) lookup(name string) (*ex.Expr, bool) {
+	return ir.lookupIn(ir.varsEnvironment, name)
+}
+
+// lookupIn returns value of the symbol in the nearest scope that contains it starting from vars
+func (ir *interpreter) lookupIn(vars *ex.Vars, name string) (*ex.Expr, bool) {
+	if _, ok := ir.shadowed[name]; !ok {
+		expr, ok := ir.globals.CurSymbols[name]
+		return expr, ok
+	}
+
+	for curEnv := vars; curEnv != nil; curEnv = curEnv.Parent {
+		if expr, ok := curEnv.Get(name); ok {
+			return expr, true
+		}
+	}
+
+	return nil, false
+}
+
+// findScope returns the nearest scope that contains the symbol or nil
+func (ir *interpreter) findScope(name string) *ex.Vars {
+	if _, ok := ir.shadowed[name]; !ok {
+		if _, ok := ir.globals.CurSymbols[name]; ok {
+			return ir.globals
+		}
+
+		return nil
+	}
+
 	curEnv := ir.varsEnvironment
 	for curEnv != nil {
-		if expr, ok := curEnv.CurSymbols[symbol.String]; ok {
-			return expr
+		if _, ok := curEnv.Get(name); ok {
+			return curEnv
 		}
 
 		curEnv = curEnv.Parent
 	}
 
-	return ex.NewFatal(fmt.Sprintf("call: symbol '%s' is not defined", symbol.String))
+	return nil
 }
 
 func (ir *interpreter) popArgs() (f *ex.Expr, args []*ex.Expr) {
@@ -455,16 +935,29 @@ func (ir *interpreter) getCurSymbol() *ex.Expr {
 }
 
 func (ir *interpreter) execFunc(f *ex.Expr, args []*ex.Expr) {
+	ir.dataStack.Push(ir.callFunction(f, args))
+}
+
+// callFunction returns result of the function
+func (ir *interpreter) callFunction(f *ex.Expr, args []*ex.Expr) *ex.Expr {
 	fn, ok := functions[f.String]
 	if !ok {
 		panic("unexpected func " + f.String)
 	}
 
-	ir.dataStack.Push(fn.F(ir, args))
+	res := fn.F(ir, args)
+	if ir.options.StrictArithmetic && res.Type == ex.Number && (math.IsNaN(res.Number) || math.IsInf(res.Number, 0)) {
+		res = ex.NewFatal("arithmetic: non-finite result")
+	}
+
+	return res
 }
 
 func (ir *interpreter) setNewVars(vars *ex.Vars) {
-	ir.callStack.SetVars(ir.varsEnvironment)
+	// after tail calls the caller's environment is already saved
+	if ir.callStack.Last().varsEnvironment == nil {
+		ir.callStack.SetVars(ir.varsEnvironment)
+	}
 	ir.varsEnvironment = vars
 }
 
@@ -514,7 +1007,61 @@ func (ir *interpreter) pushLastCall() {
 	ir.mod = nil
 }
 
+// isTailCall reports whether current expression is a call which result is returned by current call unchanged:
+// the last expression of 'begin' or the chosen branch of 'if'. Calls of macros aren't tail because expansion of macro
+// is calculated by the caller's call
+func (ir *interpreter) isTailCall() bool {
+	if len(ir.callStack) == 0 || ir.argsNum < 2 {
+		return false
+	}
+
+	f := ir.dataStack[len(ir.dataStack)-ir.argsNum+1]
+	if f.Type != ex.Function {
+		return false
+	}
+
+	rest := ir.control.Cdr().Length()
+	switch f.String {
+	case "begin":
+		if rest != 0 {
+			return false
+		}
+	case "if":
+		if !(ir.argsNum == 3 && rest <= 1 || ir.argsNum == 4 && rest == 0) {
+			return false
+		}
+	default:
+		return false
+	}
+
+	head := ir.getCurSymbol().Car()
+	if head.Type == ex.Symbol {
+		head = ir.resolveSymbol(head)
+	}
+
+	return head.Type == ex.Function || head.Type == ex.Closure
+}
+
+// tailCall replaces current call by the call in tail position
+func (ir *interpreter) tailCall() {
+	for i := 1; i < ir.argsNum; i++ {
+		ir.dataStack.Pop()
+	}
+
+	ir.control = ir.getCurSymbol()
+	ir.argsNum = 0
+	ir.mod = nil
+}
+
 func (ir *interpreter) callClosure(closure *ex.Expr, args []*ex.Expr) {
+	if ir.options.CompileClosures {
+		if res := ir.callCompiled(closure, args); res != nil {
+			ir.dataStack.Push(res)
+			ir.popLastCallAndCheckMacro()
+			return
+		}
+	}
+
 	vars, err := closure.NewClosureVars(args)
 	if err != nil {
 		ir.dataStack.Push(ex.NewFatal(err.Error()))
@@ -522,12 +1069,264 @@ func (ir *interpreter) callClosure(closure *ex.Expr, args []*ex.Expr) {
 		return
 	}
 
+	ir.shadow(vars)
 	ir.setNewVars(vars)
-	ir.control = closure.ClosureBody()
+	// after tail calls the call evaluates the last called closure
+	name := closure.String
+	if name == "" {
+		name = "lambda"
+	}
+	ir.callStack.SetName(name)
+
+	ir.control = closure.ClosureBody(len(args))
 	ir.argsNum = 0
 	ir.mod = nil
 }
 
+// compiledPure are pure functions which are called by compiled closures
+var compiledPure = map[string]struct{}{
+	"+": {}, "-": {}, "*": {}, "/": {}, "round-to": {},
+	"sin": {}, "cos": {}, "tan": {}, "asin": {}, "acos": {}, "atan": {}, "exp": {}, "log": {},
+	"bitwise-and": {}, "bitwise-or": {}, "bitwise-xor": {}, "bitwise-not": {}, "arithmetic-shift": {},
+	"car": {}, "cdr": {}, "cons": {}, "len": {}, "identity": {}, "vector?": {}, "vector-ref": {},
+	"=": {}, ">": {}, "<": {}, "approx=": {}, "equal?": {}, "eq?": {}, "not": {}, "pair?": {}, "number?": {}, "symbol?": {},
+}
+
+// compiledDepthLimit is a maximal depth of nested not tail calls of compiled closures
+const compiledDepthLimit = 10000
+
+// compiledCacheSize is a maximal number of compiled bodies kept by interpreter, the cache is cleared when it's exceeded
+const compiledCacheSize = 4096
+
+// compiledExpr calculates compiled expression in the scope. It returns nil if expression can't be calculated by
+// compiled code, then the call is calculated by interpreter from the beginning. Compiled code has no side effects, so
+// it can be left at any moment
+type compiledExpr func(vars *ex.Vars) *ex.Expr
+
+// compiledBody is a compiled body of closure called with the number of arguments. Failed body isn't calculated by
+// compiled code anymore
+type compiledBody struct {
+	run    compiledExpr
+	failed bool
+}
+
+type compiledKey struct {
+	closure *ex.Expr
+	argsNum int
+}
+
+// compiledTailCall is returned by compiled call in tail position instead of result, the call is in tailClosure and
+// tailArgs of interpreter
+var compiledTailCall = &ex.Expr{Type: ex.Nil}
+
+// callCompiled calculates call of closure by its compiled body. Result is nil if the call can't be calculated this way
+func (ir *interpreter) callCompiled(closure *ex.Expr, args []*ex.Expr) *ex.Expr {
+	if ir.compiledDepth >= compiledDepthLimit {
+		return nil
+	}
+
+	ir.compiledDepth++
+	defer func() { ir.compiledDepth-- }()
+
+	for {
+		body := ir.compiledBody(closure, len(args))
+		if body.failed {
+			return nil
+		}
+
+		vars, err := closure.NewClosureVars(args)
+		if err != nil {
+			return nil
+		}
+
+		ir.shadow(vars)
+		res := body.run(vars)
+		if res == nil {
+			body.failed = true
+			return nil
+		}
+
+		if res != compiledTailCall {
+			return res
+		}
+
+		closure, args = ir.tailClosure, ir.tailArgs
+	}
+}
+
+func (ir *interpreter) compiledBody(closure *ex.Expr, argsNum int) *compiledBody {
+	key := compiledKey{closure: closure, argsNum: argsNum}
+	if body, ok := ir.compiled[key]; ok {
+		return body
+	}
+
+	if len(ir.compiled) >= compiledCacheSize {
+		ir.compiled = map[compiledKey]*compiledBody{}
+	}
+
+	body := &compiledBody{run: ir.compile(closure.ClosureBody(argsNum), true)}
+	ir.compiled[key] = body
+	return body
+}
+
+// compile converts expression to a tree of closures. Meaning of calls is known only on calculation because their heads
+// may be rebound, so arguments of calls in tail position are compiled both as tail and not tail ones
+func (ir *interpreter) compile(expr *ex.Expr, tail bool) compiledExpr {
+	switch expr.Type {
+	case ex.Symbol:
+		return func(vars *ex.Vars) *ex.Expr {
+			res := ir.resolveSymbolIn(vars, expr)
+			if res.Type == ex.Fatal {
+				return nil
+			}
+
+			return res
+		}
+
+	case ex.Pair:
+		elems, ok := expr.ToSlice()
+		if !ok {
+			return func(vars *ex.Vars) *ex.Expr { return nil }
+		}
+
+		head := ir.compile(elems[0], false)
+		data := elems[1:]
+		args := make([]compiledExpr, len(data))
+		tailArgs := args
+		if tail {
+			tailArgs = make([]compiledExpr, len(data))
+		}
+
+		for i, arg := range data {
+			args[i] = ir.compile(arg, false)
+			if tail {
+				tailArgs[i] = ir.compile(arg, true)
+			}
+		}
+
+		return func(vars *ex.Vars) *ex.Expr {
+			f := head(vars)
+			if f == nil {
+				return nil
+			}
+
+			return ir.callCompiledExpr(vars, f, data, args, tailArgs, tail)
+		}
+
+	case ex.Fatal:
+		return func(vars *ex.Vars) *ex.Expr { return nil }
+	}
+
+	return func(vars *ex.Vars) *ex.Expr { return expr }
+}
+
+// callCompiledExpr calculates call of function f. Data are not calculated arguments of the call, args and tailArgs are
+// compiled arguments
+func (ir *interpreter) callCompiledExpr(vars *ex.Vars, f *ex.Expr, data []*ex.Expr, args, tailArgs []compiledExpr, tail bool) *ex.Expr {
+	switch f.Type {
+	case ex.Function:
+		switch f.String {
+		case "quote":
+			if len(data) != 1 {
+				return nil
+			}
+
+			return data[0]
+
+		case "if":
+			if len(args) != 2 && len(args) != 3 {
+				return nil
+			}
+
+			cond := args[0](vars)
+			if cond == nil {
+				return nil
+			}
+
+			if !ir.isFalse(cond) {
+				return tailArgs[1](vars)
+			}
+
+			if len(args) == 2 {
+				return ex.NewNil()
+			}
+
+			return tailArgs[2](vars)
+
+		case "begin":
+			if len(args) == 0 {
+				return ex.NewNil()
+			}
+
+			for _, arg := range args[:len(args)-1] {
+				if arg(vars) == nil {
+					return nil
+				}
+			}
+
+			return tailArgs[len(args)-1](vars)
+
+		case "and", "or":
+			res := ex.NewNil()
+			if f.String == "and" {
+				res = ex.NewT()
+			}
+
+			for _, arg := range args {
+				if res = arg(vars); res == nil || ir.isFalse(res) == (f.String == "and") {
+					return res
+				}
+			}
+
+			if f.String == "or" {
+				return ex.NewNil()
+			}
+
+			return res
+		}
+
+		if _, ok := compiledPure[f.String]; !ok {
+			return nil
+		}
+
+		values := ir.calculateCompiled(vars, args)
+		if values == nil {
+			return nil
+		}
+
+		if res := ir.callFunction(f, values); res.Type != ex.Fatal {
+			return res
+		}
+
+	case ex.Closure:
+		values := ir.calculateCompiled(vars, args)
+		if values == nil {
+			return nil
+		}
+
+		if tail {
+			ir.tailClosure, ir.tailArgs = f, values
+			return compiledTailCall
+		}
+
+		return ir.callCompiled(f, values)
+	}
+
+	return nil
+}
+
+// calculateCompiled returns values of compiled arguments or nil
+func (ir *interpreter) calculateCompiled(vars *ex.Vars, args []compiledExpr) []*ex.Expr {
+	values := make([]*ex.Expr, len(args))
+	for i, arg := range args {
+		if values[i] = arg(vars); values[i] == nil {
+			return nil
+		}
+	}
+
+	return values
+}
+
 func (ir *interpreter) callMacro(macro *ex.Expr, args []*ex.Expr) {
 	vars, err := macro.NewClosureVars(args)
 	if err != nil {
@@ -537,8 +1336,9 @@ func (ir *interpreter) callMacro(macro *ex.Expr, args []*ex.Expr) {
 	}
 
 	ir.callStack.SetMod(&Mod{Type: ModMacro, Old: ir.callStack.Last().mod})
+	ir.shadow(vars)
 	ir.setNewVars(vars)
-	ir.control = macro.ClosureBody()
+	ir.control = macro.ClosureBody(len(args))
 	ir.argsNum = 0
 	ir.mod = nil
 }
@@ -1,11 +1,16 @@
 package interpreter
 
 import (
+	"io/ioutil"
 	"math"
+	"os"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	ex "github.com/batrSens/LispXS/expressions"
+	"github.com/batrSens/LispXS/parser"
 
 	"github.com/magiconair/properties/assert"
 )
@@ -490,4 +495,2261 @@ func TestInterpreter(t *testing.T) {
 	assert.Equal(t, err, nil)
 	assert.Equal(t, res.Output.Equal(ex.NewNumber(10)), true, "test#"+strconv.Itoa(test))
 
+	test++ // 57 = numeric chain
+	res, err = Execute("(= 1 1.0 2/2)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewT()), true, "test#"+strconv.Itoa(test))
+
+	test++ // 58 = numeric chain, only last differs
+	res, err = Execute("(= 3 3 3 4)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNil()), true, "test#"+strconv.Itoa(test))
+
+	test++ // 59 = number compared with symbol
+	res, err = Execute("(= 3 3 '|3|)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	res, err = Execute("(catch (= '|2| 2) (default error_description))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("=: expected numbers, given symbol 2")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 60 if-let with bound value used in then branch
+	res, err = Execute("(define x 100) (if-let (x (car '(3 4))) (* x 2) 'none)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(6)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 61 if-let with nil value selects else
+	res, err = Execute("(if-let (x (cdr '(3))) x 'none)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("none")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 62 if-let binding doesn't leak out of scope
+	res, err = Execute("(define x 100) (if-let (x 7) x) x")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(100)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 63 when-let
+	res, err = Execute("(when-let (x (+ 4 5)) (define y x) y)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(9)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 64 when-let with nil value
+	res, err = Execute("(when-let (x nil) (throw 'unreachable))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNil()), true, "test#"+strconv.Itoa(test))
+
+	test++ // 65 identity passed to map
+	res, err = Execute(`(define list (lambda args args))
+		(defmacro map (f1 ,args1)
+			(define helper (lambda (f args)
+				(if args
+					(cons (list f (list quote (car args))) (helper f (cdr args))))))
+			(cons list (helper f1 args1)))
+		(map identity '(1 a ()))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(1), ex.NewSymbol("a"), ex.NewNil())), true, "test#"+strconv.Itoa(test))
+
+	test++ // 66 const called with varying arguments
+	res, err = Execute("(define k (const 'k)) (cons (k) (cons (k 1) (cons (k 1 '(2) 'three) nil)))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewSymbol("k"), ex.NewSymbol("k"), ex.NewSymbol("k"))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 67 const keeps value unevaluated
+	res, err = Execute("((const '(+ 1)) 2)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewSymbol("+"), ex.NewNumber(1))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 68 find first even number
+	res, err = Execute(`(define even? (lambda (n) (if (= n 0) T (if (= n 1) nil (even? (- n 2))))))
+		(find even? '(1 3 4 5 6))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(4)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 69 list-index of first even number
+	res, err = Execute(`(define even? (lambda (n) (if (= n 0) T (if (= n 1) nil (even? (- n 2))))))
+		(list-index even? '(1 3 4 5 6))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(2)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 70 find without match
+	res, err = Execute("(find (lambda (x) (> x 10)) '(1 3 4 5 6))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNil()), true, "test#"+strconv.Itoa(test))
+
+	test++ // 71 list-index without match
+	res, err = Execute("(list-index (lambda (x) (> x 10)) '(1 3 4 5 6))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNil()), true, "test#"+strconv.Itoa(test))
+
+	test++ // 72 find doesn't evaluate found element again
+	res, err = Execute("(find pair? '(a (b) c))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewSymbol("b"))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 73 find with not a list
+	res, err = Execute("(find pair? 5)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	// predicate is checked before the list, even an empty one
+	res, err = Execute("(cons (catch (find 5 nil) (default error_description)) (cons (catch (list-index 'a '(1)) (default error_description)) nil))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewSymbol("find: expected function, given 5"),
+		ex.NewSymbol("list-index: expected function, given a"))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 74 take
+	res, err = Execute("(take '(1 2 3) 2)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(1), ex.NewNumber(2))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 75 take more than the length
+	res, err = Execute("(take '(1 2 3) 10)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(1), ex.NewNumber(2), ex.NewNumber(3))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 76 drop
+	res, err = Execute("(drop '(1 2 3) 2)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(3))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 77 drop more than the length
+	res, err = Execute("(drop '(1 2 3) 10)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNil()), true, "test#"+strconv.Itoa(test))
+
+	test++ // 78 take with negative count
+	res, err = Execute("(take '(1 2 3) -1)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 79 iota
+	res, err = Execute("(iota 5)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(0), ex.NewNumber(1), ex.NewNumber(2), ex.NewNumber(3), ex.NewNumber(4))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 80 iota with start and step
+	res, err = Execute("(iota 3 3 -2)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(3), ex.NewNumber(1), ex.NewNumber(-1))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 81 iota with zero count
+	res, err = Execute("(iota 0)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNil()), true, "test#"+strconv.Itoa(test))
+
+	test++ // 82 iota with fractional count
+	res, err = Execute("(iota 2.5)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 83 count positive numbers
+	res, err = Execute("(count (lambda (x) (> x 0)) '(1 -2 3 0 4 -5))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(3)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 84 count in empty list
+	res, err = Execute("(count (lambda (x) (> x 0)) '())")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(0)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 85 count with not a function
+	res, err = Execute("(count 5 '(1 2))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 86 assoc-set updates existing key
+	res, err = Execute("(assoc-set '((a 1) (b 2) (c 3)) 'b 20)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewList(ex.NewSymbol("a"), ex.NewNumber(1)), ex.NewList(ex.NewSymbol("b"), ex.NewNumber(20)), ex.NewList(ex.NewSymbol("c"), ex.NewNumber(3)))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 87 assoc-set adds new key
+	res, err = Execute("(assoc-set '((a 1)) 'c 3)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewList(ex.NewSymbol("c"), ex.NewNumber(3)), ex.NewList(ex.NewSymbol("a"), ex.NewNumber(1)))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 88 assoc-set doesn't change original list
+	res, err = Execute("(define alist '((a 1) (b 2))) (assoc-set alist 'a 10) alist")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewList(ex.NewSymbol("a"), ex.NewNumber(1)), ex.NewList(ex.NewSymbol("b"), ex.NewNumber(2)))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 89 assoc-set with incorrect association list
+	res, err = Execute("(assoc-set '(a b) 'a 1)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 90 equal? nested lists
+	res, err = Execute("(equal? '(1 (2 (a |b c|)) ()) (cons 1 (cons (cons 2 (cons (cons 'a (cons (+ 'b '| c|) nil)) nil)) '(()))))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewT()), true, "test#"+strconv.Itoa(test))
+
+	test++ // 91 equal? different nested lists
+	res, err = Execute("(equal? '(1 (2 (3))) '(1 (2 (4))))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNil()), true, "test#"+strconv.Itoa(test))
+
+	test++ // 92 equal? symbols by content
+	res, err = Execute("(equal? '|hello world| (+ 'hello '| | 'world))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewT()), true, "test#"+strconv.Itoa(test))
+
+	test++ // 93 equal? number and symbol
+	res, err = Execute("(equal? 1 '|1|)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNil()), true, "test#"+strconv.Itoa(test))
+
+	test++ // 94 flatten nested list
+	res, err = Execute("(flatten '(1 (2 (3)) 4))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(1), ex.NewNumber(2), ex.NewNumber(3), ex.NewNumber(4))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 95 flatten list without nesting
+	res, err = Execute("(flatten '(a b))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewSymbol("a"), ex.NewSymbol("b"))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 96 flatten drops empty lists
+	res, err = Execute("(flatten '(() (a ()) ((b))))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewSymbol("a"), ex.NewSymbol("b"))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 97 lambda with omitted optional argument
+	res, err = Execute("(define f (lambda (a #!optional (b 10)) (+ a b))) (f 1)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(11)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 98 lambda with given optional argument
+	res, err = Execute("(define f (lambda (a #!optional (b (throw 'unreachable))) (+ a b))) (f 1 2)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(3)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 99 default value of optional argument uses previous arguments
+	res, err = Execute("(define f (lambda (a #!optional (b (* a 2)) c) (cons a (cons b (cons c nil))))) (f 2)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(2), ex.NewNumber(4), ex.NewNil())), true, "test#"+strconv.Itoa(test))
+
+	test++ // 100 lambda with too many arguments for optional
+	res, err = Execute("((lambda (a #!optional b) a) 1 2 3)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 101 lambda with too few arguments for optional
+	res, err = Execute("((lambda (a #!optional b) a))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 102 dynamic-wind on normal return
+	res, err = Execute("(dynamic-wind (lambda () (write 'before)) (lambda () (write 'thunk) 5) (lambda () (write 'after)))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(5)) && res.Stdout == "beforethunkafter", true, "test#"+strconv.Itoa(test))
+
+	test++ // 103 dynamic-wind on caught throw
+	res, err = Execute(`(catch
+			(dynamic-wind
+				(lambda () (write 'before))
+				(lambda () (throw 'oops 7) (write 'unreachable))
+				(lambda () (write 'after)))
+			(oops))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(7)) && res.Stdout == "beforeafter", true, "test#"+strconv.Itoa(test))
+
+	test++ // 104 dynamic-wind on uncaught error
+	res, err = Execute("(dynamic-wind (lambda () nil) (lambda () ((lambda () (/ 2 0)))) (lambda () (write 'after)))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")) && res.Stdout == "after" && strings.HasPrefix(res.Stderr, "FATAL: /: zero division"), true, "test#"+strconv.Itoa(test))
+
+	test++ // 105 with-output-to-string
+	res, err = Execute(`(write 'a)
+		(define s (with-output-to-string (lambda ()
+			(write 'b)
+			(write '(1 2))
+			(define inner (with-output-to-string (lambda () (write 'd))))
+			(write (+ '[ inner '])))))
+		(write 'c)
+		s`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("b(1 2)[d]")) && res.Stdout == "ac", true, "test#"+strconv.Itoa(test))
+
+	test++ // 106 with-output-to-string restores output after error
+	res, err = Execute(`(write 'a)
+		(catch (with-output-to-string (lambda () (write 'b) (throw 'oops 1))) (oops))
+		(write 'c)
+		(catch (with-output-to-string (lambda () (write 'b) (throw 'oops 1))) (oops))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(1)) && res.Stdout == "ac", true, "test#"+strconv.Itoa(test))
+
+	test++ // 107 list-max
+	res, err = Execute("(list-max '(3 -1 9 2))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(9)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 108 list-min
+	res, err = Execute("(list-min '(3 -1 9 2))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(-1)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 109 list-max of empty list
+	res, err = Execute("(list-max nil)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 110 list-min with not a number
+	res, err = Execute("(list-min '(3 a))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 111 bound? for defined symbol
+	res, err = Execute("(define x 5) (bound? x)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewT()), true, "test#"+strconv.Itoa(test))
+
+	test++ // 112 bound? for undefined symbol
+	res, err = Execute("(bound? undefined-symbol)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNil()), true, "test#"+strconv.Itoa(test))
+
+	test++ // 113 bound? for symbol from outer scope
+	res, err = Execute(`(define x 5)
+		(define f (lambda (y) (define z 1) (cons (bound? x) (cons (bound? z) nil))))
+		(cons (car (f 1)) (cons (car (cdr (f 1))) (cons (bound? z) nil)))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewT(), ex.NewT(), ex.NewNil())), true, "test#"+strconv.Itoa(test))
+
+	test++ // 114 unset! returns prior value
+	res, err = Execute("(define x 5) (unset! x)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(5)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 115 reference to unset symbol
+	res, err = Execute("(define x 5) (unset! x) x")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 116 unset! of undefined symbol
+	res, err = Execute("(unset! undefined-symbol)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 117 unset! removes the nearest binding
+	res, err = Execute("(define x 1) ((lambda (x) (unset! x) x) 2)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(1)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 118 set! of variable from second outer scope
+	res, err = Execute("(define x 1) (((lambda () (lambda () (set! x 3))))) x")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(3)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 119 letrec* with later binding referencing earlier one
+	res, err = Execute("(letrec* ((a 5) (b (+ a 1))) (* b 2))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(12)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 120 letrec* with recursive and forward references
+	res, err = Execute(`(letrec* (
+			(even? (lambda (n) (if (= n 0) T (odd? (- n 1)))))
+			(odd? (lambda (n) (if (= n 0) nil (even? (- n 1)))))
+			(res (cons (even? 10) (cons (odd? 10) nil))))
+			res)`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewT(), ex.NewNil())), true, "test#"+strconv.Itoa(test))
+
+	test++ // 121 letrec* bindings aren't visible outside
+	res, err = Execute("(letrec* ((a 5)) a) (bound? a)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNil()), true, "test#"+strconv.Itoa(test))
+
+	test++ // 122 letrec* with forward reference to uninstalled binding
+	res, err = Execute("(letrec* ((a b) (b 1)) a)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 123 zip two lists
+	res, err = Execute("(zip '(1 2) '(a b))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewList(ex.NewNumber(1), ex.NewSymbol("a")), ex.NewList(ex.NewNumber(2), ex.NewSymbol("b")))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 124 zip lists of unequal length
+	res, err = Execute("(zip '(1 2 3) '(4 5) '(6))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewList(ex.NewNumber(1), ex.NewNumber(4), ex.NewNumber(6)))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 125 unzip zipped lists
+	res, err = Execute("(unzip (zip '(1 2 3) '(a b c)))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewList(ex.NewNumber(1), ex.NewNumber(2), ex.NewNumber(3)), ex.NewList(ex.NewSymbol("a"), ex.NewSymbol("b"), ex.NewSymbol("c")))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 126 zip of improper argument
+	res, err = Execute("(zip '(1 2) 3)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 127 unzip of lists of different length
+	res, err = Execute("(unzip '((1 2) (3)))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 128 approx= with difference below tolerance
+	res, err = Execute("(approx= (+ 0.1 0.2) 0.3 1e-9)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewT()), true, "test#"+strconv.Itoa(test))
+
+	test++ // 129 approx= with difference above tolerance
+	res, err = Execute("(approx= 1 1.1 0.01)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNil()), true, "test#"+strconv.Itoa(test))
+
+	test++ // 130 approx= with non-number
+	res, err = Execute("(approx= 1 '|1| 0.1)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 131 make-list of five zeros
+	res, err = Execute("(make-list 5 0)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(0), ex.NewNumber(0), ex.NewNumber(0), ex.NewNumber(0), ex.NewNumber(0))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 132 make-list of zero length
+	res, err = Execute("(make-list 0 'a)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNil()), true, "test#"+strconv.Itoa(test))
+
+	test++ // 133 make-list with default fill
+	res, err = Execute("(make-list 2)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNil(), ex.NewNil())), true, "test#"+strconv.Itoa(test))
+
+	test++ // 134 make-list with negative length
+	res, err = Execute("(make-list -1 0)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 135 string-trim of spaces
+	res, err = Execute("(string-trim '|  a b	 |)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("a b")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 136 string-trim-left and string-trim-right
+	res, err = Execute(`(+ (string-trim-left '|  a  |) '|\|| (string-trim-right '|  a  |))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("a  |  a")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 137 string-trim with custom cutset
+	res, err = Execute("(string-trim '|*-*a-b-*| '|*-|)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("a-b")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 138 string-trim-right with custom cutset
+	res, err = Execute("(string-trim-right '|--a--| '|-|)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("--a")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 139 string-trim of non-symbol
+	res, err = Execute("(string-trim 1)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 140 format with ~a
+	res, err = Execute("(format '|x = ~a, ~a| '|a b| '(1 c))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("x = a b, (1 c)")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 141 format with ~s
+	res, err = Execute(`(format '|~s ~s ~s ~s| '|a b| '(1 c) '|\|| 2)`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("|a b| (1 |c|) |\\|| 2")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 142 format with ~d, ~% and ~~
+	res, err = Execute("(format '|~d~~~%| 1.5)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("1.5~\n")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 143 format with not number for ~d
+	res, err = Execute("(format '|~d| 'a)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 144 format with too few arguments
+	res, err = Execute("(format '|~a ~a| 1)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 145 format with too many arguments
+	res, err = Execute("(format '|~a| 1 2)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 146 error in function mapped by map macro stops evaluation at third element
+	res, err = Execute(`(define list (lambda args args))
+(defmacro map (f1 ,args1)
+  (define helper (lambda (f args)
+    (if args
+      (cons (list f (list quote (car args))) (helper f (cdr args))))))
+  (cons 'list (helper f1 args1)))
+(map (lambda (x) (write x) (if (= x 3) (throw 'third) x)) '(1 2 3 4 5))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")) && res.Stdout == "123" && strings.HasPrefix(res.Stderr, "FATAL: third"), true, "test#"+strconv.Itoa(test))
+
+	test++ // 147 error in predicate of count stops evaluation at third element
+	res, err = Execute("(count (lambda (x) (write x) (if (= x 3) (/ x 0) T)) '(1 2 3 4 5))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")) && res.Stdout == "123", true, "test#"+strconv.Itoa(test))
+
+	test++ // 148 error in predicate of find is caught at the third element
+	res, err = Execute(`(catch (find (lambda (x) (write x) (if (= x 3) (/ x 0))) '(1 2 3 4 5))
+  (/ 'caught))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("caught")) && res.Stdout == "123", true, "test#"+strconv.Itoa(test))
+
+	test++ // 149 append! of two lists
+	res, err = Execute(`(define a (cons 1 (cons 2 nil)))
+(append! a (cons 3 (cons 4 nil)))
+a`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(1), ex.NewNumber(2), ex.NewNumber(3), ex.NewNumber(4))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 150 list appended by append! shares structure with second list
+	res, err = Execute(`(define a (cons 1 nil))
+(define b (cons 2 nil))
+(append! a b)
+(append! b (cons 3 nil))
+a`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(1), ex.NewNumber(2), ex.NewNumber(3))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 151 append! with empty first list
+	res, err = Execute("(append! nil (cons 1 nil))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(1))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 152 append! with circular list
+	res, err = Execute(`(define a (cons 1 (cons 2 nil)))
+(append! a a)
+(append! a nil)`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 153 append! with improper argument
+	res, err = Execute("(append! 1 nil)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 154 reverse! of list
+	res, err = Execute("(reverse! (cons 1 (cons 2 (cons 3 nil))))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(3), ex.NewNumber(2), ex.NewNumber(1))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 155 original head after reverse! becomes last pair
+	res, err = Execute(`(define a (cons 1 (cons 2 (cons 3 nil))))
+(reverse! a)
+a`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(1))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 156 reverse! of empty and single-element lists
+	res, err = Execute("(cons (reverse! nil) (cons (reverse! (cons 1 nil)) nil))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNil(), ex.NewList(ex.NewNumber(1)))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 157 reverse! of improper argument
+	res, err = Execute("(reverse! 'a)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 158 pp of short list
+	res, err = Execute("(pp '(1 2))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(1), ex.NewNumber(2))) && res.Stdout == "(1 2)\n", true, "test#"+strconv.Itoa(test))
+
+	test++ // 159 pp of long nested list
+	res, err = Execute("(pp '(define fact (lambda (n) (if (= n 0) 1 (* n (fact (- n 1)))))))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Stdout == "(define\n  fact\n  (lambda\n    (n)\n    (if (= n 0) 1 (* n (fact (- n 1))))))\n", true, "test#"+strconv.Itoa(test))
+
+	test++ // 160 division with non-number dividend
+	res, err = Execute("(/ 'x 1)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 161 division of numbers
+	res, err = Execute("(/ 10 2 2)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(2.5)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 162 overflowing multiplication in strict arithmetic mode
+	res, err = ExecuteWithOptions("(* 1e200 1e200)", Options{StrictArithmetic: true})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 163 overflowing multiplication without strict arithmetic mode
+	res, err = Execute("(* 1e200 1e200)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(math.Inf(1))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 164 infinite quotient in strict arithmetic mode is catchable
+	res, err = ExecuteWithOptions("(catch (/ 1 1e-320) (arithmetic 'caught))", Options{StrictArithmetic: true})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("caught")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 165 finite arithmetic in strict arithmetic mode
+	res, err = ExecuteWithOptions("(* (+ 1 2) (/ 4 2))", Options{StrictArithmetic: true})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(6)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 166 cond selects first true clause
+	res, err = Execute(`(define x 2)
+(cond ((= x 1) 'one) ((= x 2) (write 'a) 'two) (else 'other))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("two")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 167 cond with else and without matching clauses
+	res, err = Execute("(cons (cond (nil 1) (else 'other)) (cons (cond (nil 1)) nil))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewSymbol("other"), ex.NewNil())), true, "test#"+strconv.Itoa(test))
+
+	test++ // 168 cond with clause without body returns value of test
+	res, err = Execute("(cond (nil 1) ((+ 1 2)) (else 4))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(3)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 169 cond with => applies procedure to value of test
+	res, err = Execute(`(define alist '((a 1) (b 2)))
+(define key 'b)
+(cond ((find (lambda (p) (= (car p) key)) alist) => (lambda (p) (car (cdr p)))) (else 'none))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(2)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 170 cond with => and missing key
+	res, err = Execute(`(define alist '((a 1) (b 2)))
+(cond ((find (lambda (p) (= (car p) 'c)) alist) => car) (else 'none))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("none")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 171 cond evaluates test once
+	res, err = Execute("(cond ((write 'a) => identity))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("a")) && res.Stdout == "a", true, "test#"+strconv.Itoa(test))
+
+	test++ // 172 cond with incorrect clause
+	res, err = Execute("(cond 1)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 173 case with symbol data
+	res, err = Execute("(case (car '(e x)) ((a e i o u) 'vowel) ((w y) 'semivowel) (else 'consonant))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("vowel")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 174 case with else and without matching clauses
+	res, err = Execute("(cons (case 5 ((1 2 3) 'small) (else 'other)) (cons (case 5 ((1) 'one)) nil))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewSymbol("other"), ex.NewNil())), true, "test#"+strconv.Itoa(test))
+
+	test++ // 175 case dispatches numeric key to range clause
+	res, err = Execute(`(define age 15)
+(case age (((0 12)) 'child) (((13 19)) 'teen) (else 'adult))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("teen")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 176 case with => transforms matched key
+	res, err = Execute("(case (+ 3 4) (((0 9)) => (lambda (x) (* x x))) (else 0))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(49)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 177 case with incorrect clause
+	res, err = Execute("(case 1 1)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 178 tail-recursive loop via cond
+	res, err = Execute(`(define loop (lambda (n) (cond ((= n 0) 'done) (else (loop (- n 1))))))
+(loop 100000)`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("done")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 179 tail-recursive loop via case with accumulator
+	res, err = Execute(`(define sum (lambda (n acc) (case n ((0) acc) (else (sum (- n 1) (+ acc n))))))
+(sum 100000 0)`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(5000050000)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 180 environment of caller is restored after chain of tail calls
+	res, err = Execute(`(define x 'outer)
+(define g (lambda (x) x))
+(define f (lambda () (define x 'inner) (g x)))
+(define h (lambda () (f)))
+(cons (h) (cons x nil))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewSymbol("inner"), ex.NewSymbol("outer"))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 181 macro in tail position is expanded in scope of closure
+	res, err = Execute(`(defmacro get-a () 'a)
+(define f (lambda (a) (if T (get-a))))
+(f 5)`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(5)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 182 passing assert
+	res, err = Execute("(assert (= (+ 1 2) 3))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewT()), true, "test#"+strconv.Itoa(test))
+
+	test++ // 183 failing assert with custom message
+	res, err = Execute(`(define x -1)
+(assert (> x 0) '|x must be positive|)`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")) && strings.HasPrefix(res.Stderr, "FATAL: assert: x must be positive\n"), true, "test#"+strconv.Itoa(test))
+
+	test++ // 184 failing assert with default message
+	res, err = Execute(`(define x -1)
+(assert (> x 0))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")) && strings.HasPrefix(res.Stderr, "FATAL: assert: failed (> x 0)\n"), true, "test#"+strconv.Itoa(test))
+
+	test++ // 185 failing assert is catchable
+	res, err = Execute("(catch (assert nil) (assert 'caught))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("caught")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 186 begin0 returns first value after side effects of the rest
+	res, err = Execute(`(define x 1)
+(begin0 x (write 'a) (set! x 2) (write 'b))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(1)) && res.Stdout == "ab", true, "test#"+strconv.Itoa(test))
+
+	test++ // 187 begin0 without arguments
+	res, err = Execute("(begin0)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 188 force calculates delayed expression only once
+	res, err = Execute(`(define p (delay (begin (write 'a) (+ 1 2))))
+(cons (force p) (cons (force p) nil))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(3), ex.NewNumber(3))) && res.Stdout == "a", true, "test#"+strconv.Itoa(test))
+
+	test++ // 189 delay doesn't calculate expression
+	res, err = Execute(`(delay (write 'a))
+'ok`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("ok")) && res.Stdout == "", true, "test#"+strconv.Itoa(test))
+
+	test++ // 190 delayed expression is calculated in scope of delay
+	res, err = Execute(`(define make (lambda (x) (delay (* x 5))))
+(define x 100)
+(force (make 1))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(5)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 191 force of not a promise returns it
+	res, err = Execute("(force 1)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(1)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 192 error in delayed expression falls through force
+	res, err = Execute("(force (delay (/ 1 0)))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 193 infinite stream of integers
+	res, err = Execute(`(define integers (lambda (n) (cons-stream n (integers (+ n 1)))))
+(define take (lambda (s n)
+	(if (= n 0)
+		nil
+		(cons (stream-car s) (take (stream-cdr s) (- n 1))))))
+(take (integers 0) 5)`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(0), ex.NewNumber(1), ex.NewNumber(2), ex.NewNumber(3), ex.NewNumber(4))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 194 tail of stream is calculated once
+	res, err = Execute(`(define s (cons-stream 1 (begin (write 't) (cons-stream 2 nil))))
+(stream-cdr s)
+(stream-car (stream-cdr s))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(2)) && res.Stdout == "t", true, "test#"+strconv.Itoa(test))
+
+	test++ // 195 stream-cdr of not a stream
+	res, err = Execute("(stream-cdr '(1 2))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 196 stream-take from infinite stream
+	res, err = Execute(`(define integers (lambda (n) (cons-stream n (begin (write (+ n 1)) (integers (+ n 1))))))
+(stream-take (integers 0) 3)`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(0), ex.NewNumber(1), ex.NewNumber(2))) && res.Stdout == "12", true, "test#"+strconv.Itoa(test))
+
+	test++ // 197 stream-take from finite stream
+	res, err = Execute("(stream-take (cons-stream 1 (cons-stream 2 nil)) 5)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(1), ex.NewNumber(2))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 198 stream-ref of infinite stream
+	res, err = Execute(`(define integers (lambda (n) (cons-stream n (integers (+ n 1)))))
+(stream-ref (integers 0) 1000)`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(1000)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 199 stream-ref out of finite stream
+	res, err = Execute("(stream-ref (cons-stream 1 nil) 1)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 200 memoized function is called once per distinct arguments
+	res, err = Execute(`(define add (memoize (lambda (a b) (write (cons a (cons b nil))) (+ a b))))
+(cons (add 1 2) (cons (add 1 2) (cons (add 2 3) (cons (add 1 2) nil))))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(3), ex.NewNumber(3), ex.NewNumber(5), ex.NewNumber(3))) && res.Stdout == "(1 2)(2 3)", true, "test#"+strconv.Itoa(test))
+
+	test++ // 201 memoized recursive function
+	res, err = Execute(`(define fib (memoize (lambda (n) (if (< n 2) n (+ (fib (- n 1)) (fib (- n 2)))))))
+(fib 50)`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(12586269025)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 202 memoized function with list arguments
+	res, err = Execute(`(define f (memoize (lambda (l) (write 'x) (car (cdr l)))))
+(f '(1 2))
+(f '(1 2))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(2)) && res.Stdout == "x", true, "test#"+strconv.Itoa(test))
+
+	test++ // 203 memoize of not a function
+	res, err = Execute("(memoize 1)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 204 map over lists of different length stops at the shortest
+	res, err = Execute("(map + '(1 2 3) '(10 20 30 40 50))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(11), ex.NewNumber(22), ex.NewNumber(33))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 205 map with not a list
+	res, err = Execute("(map + '(1 2 3) 5)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 206 map over one list with closure
+	res, err = Execute("(map (lambda (x) (* x x)) '(1 2 3))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(1), ex.NewNumber(4), ex.NewNumber(9))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 207 list converted to vector and back
+	res, err = Execute("(define v (list->vector '(1 a (2 3)))) (cons (vector? v) (cons (vector-ref v 1) (vector->list v)))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewT(), ex.NewSymbol("a"), ex.NewNumber(1), ex.NewSymbol("a"),
+		ex.NewList(ex.NewNumber(2), ex.NewNumber(3)))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 208 vector converted to list and back is equal to the original
+	res, err = Execute("(define v (list->vector '(1 2 3))) (equal? v (list->vector (vector->list v)))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewT()), true, "test#"+strconv.Itoa(test))
+
+	test++ // 209 ranged vector->list
+	res, err = Execute("(define v (list->vector '(0 1 2 3 4))) (cons (vector->list v 2) (vector->list v 1 3))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewList(ex.NewNumber(2), ex.NewNumber(3), ex.NewNumber(4)),
+		ex.NewNumber(1), ex.NewNumber(2))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 210 vector->list with incorrect range
+	res, err = Execute("(vector->list (list->vector '(0 1 2)) 2 4)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 211 vector->list of not a vector
+	res, err = Execute("(vector->list '(1 2))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 212 list->vector of not a list
+	res, err = Execute("(list->vector 5)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 213 vector-map squares elements
+	res, err = Execute("(vector-map (lambda (x) (* x x)) (list->vector '(1 2 3)))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewVector(ex.NewNumber(1), ex.NewNumber(4), ex.NewNumber(9))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 214 vector-map over two vectors stops at the shortest
+	res, err = Execute("(vector-map + (list->vector '(1 2 3)) (list->vector '(10 20)))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewVector(ex.NewNumber(11), ex.NewNumber(22))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 215 vector-for-each calls function in order of elements
+	res, err = Execute("(vector-for-each (lambda (x y) (write x) (write y)) (list->vector '(a b)) (list->vector '(1 2 3)))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNil()) && res.Stdout == "a1b2", true, "test#"+strconv.Itoa(test))
+
+	test++ // 216 vector-map of not a vector
+	res, err = Execute("(vector-map - '(1 2))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 217 vector-fill! changes vector in place
+	res, err = Execute("(define v (list->vector '(1 2 3))) (vector-fill! v 'x) v")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewVector(ex.NewSymbol("x"), ex.NewSymbol("x"), ex.NewSymbol("x"))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 218 vector-copy of range
+	res, err = Execute("(vector-copy (list->vector '(0 1 2 3 4)) 1 4)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewVector(ex.NewNumber(1), ex.NewNumber(2), ex.NewNumber(3))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 219 copy isn't changed by changes of source
+	res, err = Execute(`(define v (list->vector '(1 2 3)))
+(define c (vector-copy v))
+(vector-fill! v 0)
+(cons c (cons v nil))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewVector(ex.NewNumber(1), ex.NewNumber(2), ex.NewNumber(3)),
+		ex.NewVector(ex.NewNumber(0), ex.NewNumber(0), ex.NewNumber(0)))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 220 vector-copy with incorrect range
+	res, err = Execute("(vector-copy (list->vector '(0 1 2)) 2 1)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 221 vector-fill! of not a vector
+	res, err = Execute("(vector-fill! '(1 2) 0)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 222 string-index of found substring
+	res, err = Execute("(string-index '|hello world| 'wor)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(6)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 223 string-index of absent substring
+	res, err = Execute("(string-index 'hello 'xyz)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNil()), true, "test#"+strconv.Itoa(test))
+
+	test++ // 224 string-index counts runes in multibyte string
+	res, err = Execute("(string-index '|привет мир| 'мир)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(7)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 225 string-index of not a symbol
+	res, err = Execute("(string-index 'hello 1)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 226 classification of letter
+	res, err = Execute("(cons (char-alphabetic? 'ж) (cons (char-numeric? 'ж) (cons (char-whitespace? 'ж) nil)))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewT(), ex.NewNil(), ex.NewNil())), true, "test#"+strconv.Itoa(test))
+
+	test++ // 227 classification of digit
+	res, err = Execute("(cons (char-alphabetic? '|7|) (cons (char-numeric? '|7|) (cons (char-whitespace? '|7|) nil)))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNil(), ex.NewT(), ex.NewNil())), true, "test#"+strconv.Itoa(test))
+
+	test++ // 228 classification of space
+	res, err = Execute("(cons (char-alphabetic? '| |) (cons (char-numeric? '| |) (cons (char-whitespace? '| |) nil)))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNil(), ex.NewNil(), ex.NewT())), true, "test#"+strconv.Itoa(test))
+
+	test++ // 229 classification of punctuation
+	res, err = Execute("(cons (char-alphabetic? '!) (cons (char-numeric? '!) (cons (char-whitespace? '!) nil)))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNil(), ex.NewNil(), ex.NewNil())), true, "test#"+strconv.Itoa(test))
+
+	test++ // 230 classification of symbol of several characters
+	res, err = Execute("(char-alphabetic? 'ab)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 231 read-line reads lines of input until the end
+	expr, err := ExecuteTo(`(define lines (lambda ()
+  (define line (read-line))
+  (if (not (eof-object? line)) (cons line (lines)))))
+(cons (lines) (cons (eof-object? (read-line)) nil))`, ioutil.Discard, ioutil.Discard, strings.NewReader("first line\nsecond\r\n\nlast"))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, expr.Equal(ex.NewList(ex.NewList(ex.NewSymbol("first line"), ex.NewSymbol("second"), ex.NewSymbol(""),
+		ex.NewSymbol("last")), ex.NewT())), true, "test#"+strconv.Itoa(test))
+
+	test++ // 232 read-line and read share input
+	expr, err = ExecuteTo("(cons (read-line) (read))", ioutil.Discard, ioutil.Discard, strings.NewReader("line\n(1 2) 3\n"))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, expr.Equal(ex.NewList(ex.NewSymbol("line"), ex.NewList(ex.NewNumber(1), ex.NewNumber(2)), ex.NewNumber(3))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 233 peek-char doesn't consume character read by read-char
+	expr, err = ExecuteTo("(cons (peek-char) (cons (peek-char) (cons (read-char) (cons (read-char) (cons (read-line) nil)))))",
+		ioutil.Discard, ioutil.Discard, strings.NewReader("жz rest\n"))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, expr.Equal(ex.NewList(ex.NewSymbol("ж"), ex.NewSymbol("ж"), ex.NewSymbol("ж"), ex.NewSymbol("z"),
+		ex.NewSymbol(" rest"))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 234 read-char and peek-char at the end of input
+	expr, err = ExecuteTo("(cons (read-char) (cons (peek-char) (cons (read-char) nil)))", ioutil.Discard, ioutil.Discard, strings.NewReader("a"))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, expr.Equal(ex.NewList(ex.NewSymbol("a"), ex.NewEOF(), ex.NewEOF())), true, "test#"+strconv.Itoa(test))
+
+	test++ // 235 reading past the end of input returns EOF object
+	expr, err = ExecuteTo(`(define a (read)) (define b (read-line)) (define c (read-char))
+(cons (eof-object? a) (cons (eof-object? b) (cons (eof-object? c) (cons (eof-object? (read)) nil))))`,
+		ioutil.Discard, ioutil.Discard, strings.NewReader("(1 2)\n"))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, expr.Equal(ex.NewList(ex.NewNil(), ex.NewT(), ex.NewT(), ex.NewT())), true, "test#"+strconv.Itoa(test))
+
+	test++ // 236 eof-object? of symbol
+	res, err = Execute("(eof-object? '|#<eof>|)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNil()), true, "test#"+strconv.Itoa(test))
+
+	test++ // 237 unfinished expression at the end of input
+	expr, err = ExecuteTo("(read)", ioutil.Discard, ioutil.Discard, strings.NewReader("(1 2"))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, expr.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 238 long or chain with the first not nil near the end
+	res, err = Execute("(or " + strings.Repeat("nil ", 100000) + "'found (/ 1 0))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("found")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 239 long and chain with the first nil near the end
+	res, err = Execute("(and " + strings.Repeat("T ", 100000) + "(write 'last) nil (/ 1 0))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNil()) && res.Stdout == "last", true, "test#"+strconv.Itoa(test))
+
+	test++ // 240 error in the middle of or chain
+	res, err = Execute("(catch (or nil nil (/ 1 0) 'x) (/ 'caught))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("caught")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 241 call-with-values passes values to consumer
+	res, err = Execute("(call-with-values (lambda () (values 1 2 3)) -)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(-4)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 242 floor/ of negative dividend
+	res, err = Execute("(call-with-values (lambda () (floor/ -7 2)) (lambda (q r) (cons q (cons r nil))))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(-4), ex.NewNumber(1))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 243 truncate/ of negative dividend
+	res, err = Execute("(call-with-values (lambda () (truncate/ -7 2)) (lambda (q r) (cons q (cons r nil))))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(-3), ex.NewNumber(-1))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 244 floor/ and truncate/ of positive numbers are equal
+	res, err = Execute("(equal? (floor/ 7 2) (truncate/ 7 2))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewT()), true, "test#"+strconv.Itoa(test))
+
+	test++ // 245 floor/ by zero
+	res, err = Execute("(floor/ 7 0)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 246 truncate/ of not a number
+	res, err = Execute("(truncate/ 'a 2)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 247 exact-integer-sqrt of perfect square
+	res, err = Execute("(call-with-values (lambda () (exact-integer-sqrt 144)) (lambda (s r) (cons s (cons r nil))))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(12), ex.NewNumber(0))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 248 exact-integer-sqrt of not perfect square
+	res, err = Execute("(exact-integer-sqrt 17)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(4), ex.NewNumber(1))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 249 exact-integer-sqrt of big number
+	res, err = Execute("(exact-integer-sqrt 4503599761588224)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(67108864), ex.NewNumber(134217728))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 250 exact-integer-sqrt of not integer
+	res, err = Execute("(exact-integer-sqrt 2.5)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 251 exact-integer-sqrt of negative number
+	res, err = Execute("(exact-integer-sqrt -4)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 252 bitwise-and masks bits
+	res, err = Execute("(bitwise-and 255 60 -4)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(60)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 253 bitwise-or combines flags
+	res, err = Execute("(bitwise-or 1 4 16)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(21)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 254 bitwise-xor and bitwise-not
+	res, err = Execute("(cons (bitwise-xor 12 10) (cons (bitwise-not 5) (cons (bitwise-and) (cons (bitwise-xor) nil))))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(6), ex.NewNumber(-6), ex.NewNumber(-1), ex.NewNumber(0))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 255 bitwise operation of not integer
+	res, err = Execute("(bitwise-or 1 2.5)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 256 arithmetic-shift to the left
+	res, err = Execute("(arithmetic-shift 3 4)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(48)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 257 arithmetic-shift to the right keeps sign
+	res, err = Execute("(cons (arithmetic-shift 48 -3) (cons (arithmetic-shift -7 -1) nil))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(6), ex.NewNumber(-4))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 258 arithmetic-shift by zero
+	res, err = Execute("(arithmetic-shift 13 0)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(13)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 259 arithmetic-shift by not integer
+	res, err = Execute("(arithmetic-shift 13 0.5)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 260 integer->bits of number narrower than width is padded
+	res, err = Execute("(integer->bits 5 8)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("00000101")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 261 integer->bits of number of exact width
+	res, err = Execute("(integer->bits 255 8)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("11111111")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 262 integer->bits of negative number
+	res, err = Execute("(integer->bits (bitwise-not 5) 8)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("11111010")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 263 integer->bits of number wider than width
+	res, err = Execute("(integer->bits 256 8)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 264 seeded random generator returns reproducible sequence
+	res, err = Execute(`(define seq (lambda (n) (if (> n 0) (cons (random 100) (cons (random) (seq (- n 1)))))))
+(random-seed 42)
+(define a (seq 5))
+(random-seed 42)
+(equal? a (seq 5))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewT()), true, "test#"+strconv.Itoa(test))
+
+	test++ // 265 random numbers are in range
+	res, err = Execute(`(define check (lambda (n)
+  (if (> n 0)
+    (and (< (random 3) 3) (not (< (random 3) 0)) (< (random) 1) (not (< (random) 0)) (check (- n 1)))
+    T)))
+(check 100)`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewT()), true, "test#"+strconv.Itoa(test))
+
+	test++ // 266 random with not positive bound
+	res, err = Execute("(random 0)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 267 runtime is non-negative and increases after work
+	res, err = Execute(`(define loop (lambda (n) (if (> n 0) (loop (- n 1)))))
+(define a (runtime))
+(loop 10000)
+(define b (runtime))
+(and (not (< a 0)) (< a b))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewT()), true, "test#"+strconv.Itoa(test))
+
+	test++ // 268 current-time is unix time in seconds
+	before := time.Now().Unix()
+	res, err = Execute("(current-time)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Type == ex.Number && int64(res.Output.Number) >= before && int64(res.Output.Number) <= time.Now().Unix(), true, "test#"+strconv.Itoa(test))
+
+	test++ // 269 transcendental functions of known values
+	res, err = Execute("(cons (sin 0) (cons (cos 0) (cons (tan 0) (cons (exp 0) (cons (log 1) (cons (asin 1) nil))))))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(0), ex.NewNumber(1), ex.NewNumber(0), ex.NewNumber(1),
+		ex.NewNumber(0), ex.NewNumber(math.Pi/2))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 270 atan of two arguments and log with base
+	res, err = Execute("(cons (atan 1 -1) (cons (log 8 2) (cons (atan 1) nil)))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(3*math.Pi/4), ex.NewNumber(3), ex.NewNumber(math.Pi/4))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 271 log of not positive number
+	res, err = Execute("(log 0)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 272 log with base 1
+	res, err = Execute("(log 8 1)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 273 acos out of domain
+	res, err = Execute("(acos 2)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 274 sin of not a number
+	res, err = Execute("(sin 'a)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 275 pi and e are defined at startup
+	res, err = Execute("(cons pi (cons e (cons (cos pi) nil)))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(math.Pi), ex.NewNumber(math.E), ex.NewNumber(-1))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 276 pi can be shadowed and redefined
+	res, err = Execute(`(define local ((lambda () (define pi 3) pi)))
+(define param ((lambda (e) e) 2))
+(define global pi)
+(set! e 1)
+(cons local (cons param (cons global (cons e nil))))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(3), ex.NewNumber(2), ex.NewNumber(math.Pi), ex.NewNumber(1))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 277 round-to two decimal places
+	res, err = Execute("(round-to 3.14159 2)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(3.14)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 278 round-to zero decimal places
+	res, err = Execute("(cons (round-to 3.14159 0) (cons (round-to -2.5 0) nil))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(3), ex.NewNumber(-3))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 279 round-to negative number of places
+	res, err = Execute("(round-to 3.14159 -1)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 280 reference to undefined symbol is an error by default
+	res, err = Execute("(cons undefined-symbol nil)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 281 reference to undefined symbol is nil with UnboundAsNil option
+	res, err = ExecuteWithOptions("(cons undefined-symbol (cons (bound? undefined-symbol) nil))", Options{UnboundAsNil: true})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNil(), ex.NewNil())), true, "test#"+strconv.Itoa(test))
+
+	test++ // 282 set! of undefined symbol is an error with UnboundAsNil option
+	res, err = ExecuteWithOptions("(set! undefined-symbol 1)", Options{UnboundAsNil: true})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 283 defined-symbols contains user definitions and builtins once
+	res, err = Execute(`(define alpha 1)
+(define beta 2)
+(define f (lambda (alpha gamma) (defined-symbols)))
+(define count-of (lambda (sym syms) (count (lambda (s) (= s sym)) syms)))
+(define syms (f 3 4))
+(cons (count-of 'alpha syms) (cons (count-of 'beta syms) (cons (count-of 'gamma syms) (cons (count-of 'car syms) (cons (count-of 'gamma (defined-symbols)) nil)))))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(1), ex.NewNumber(1), ex.NewNumber(1), ex.NewNumber(1), ex.NewNumber(0))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 284 trace of recursive function writes call tree
+	res, err = Execute(`(define fact (lambda (n) (if (< n 2) 1 (* n (fact (- n 1))))))
+(trace fact)
+(fact 3)`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(6)) && res.Stdout == "(fact 3)\n  (fact 2)\n    (fact 1)\n    1\n  2\n6\n", true, "test#"+strconv.Itoa(test))
+
+	test++ // 285 untrace restores function
+	res, err = Execute(`(define fact (lambda (n) (if (< n 2) 1 (* n (fact (- n 1))))))
+(trace fact)
+(untrace fact)
+(fact 3)`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(6)) && res.Stdout == "", true, "test#"+strconv.Itoa(test))
+
+	test++ // 286 untrace of not traced function
+	res, err = Execute("(untrace car)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 287 trace of builtin function
+	res, err = Execute("(trace car) (car '(1 2))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(1)) && res.Stdout == "(car (1 2))\n1\n", true, "test#"+strconv.Itoa(test))
+
+	test++ // 288 eval-in reads local variables of captured environment
+	res, err = Execute(`(define make (lambda (x) (define y (* x 2)) (capture-env)))
+(define env (make 5))
+(define x 100)
+(eval-in env '(+ x y))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(15)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 289 eval-in changes variables of captured environment by set!
+	res, err = Execute(`(define make (lambda (x) (cons (capture-env) (cons (lambda () x) nil))))
+(define pair (make 1))
+(eval-in (car pair) '(set! x 2))
+((car (cdr pair)))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(2)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 290 eval-in with not an environment
+	res, err = Execute("(eval-in 1 '(+ 1 2))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 291 mutation of nested copy made by copy-tree doesn't change original
+	res, err = Execute(`(define orig '(1 (2 (3 4)) 5))
+(define copy (copy-tree orig))
+(set-car! (car (cdr copy)) 'x)
+(set-car! (car (cdr (car (cdr copy)))) 'y)
+(cons orig (cons copy nil))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(
+		ex.NewList(ex.NewNumber(1), ex.NewList(ex.NewNumber(2), ex.NewList(ex.NewNumber(3), ex.NewNumber(4))), ex.NewNumber(5)),
+		ex.NewList(ex.NewNumber(1), ex.NewList(ex.NewSymbol("x"), ex.NewList(ex.NewSymbol("y"), ex.NewNumber(4))), ex.NewNumber(5)))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 292 copy-tree shares leaves
+	res, err = Execute(`(define v (list->vector '(1 2)))
+(define copy (copy-tree (cons v nil)))
+(vector-fill! v 0)
+(car copy)`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewVector(ex.NewNumber(0), ex.NewNumber(0))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 293 set-car! of not a pair
+	res, err = Execute("(set-car! nil 1)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 294 assoc-remove of existing key doesn't change original list
+	res, err = Execute(`(define alist '((a 1) (b 2) (a 3) (c 4)))
+(cons (assoc-remove alist 'a) (cons alist nil))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(
+		ex.NewList(ex.NewList(ex.NewSymbol("b"), ex.NewNumber(2)), ex.NewList(ex.NewSymbol("c"), ex.NewNumber(4))),
+		ex.NewList(ex.NewList(ex.NewSymbol("a"), ex.NewNumber(1)), ex.NewList(ex.NewSymbol("b"), ex.NewNumber(2)),
+			ex.NewList(ex.NewSymbol("a"), ex.NewNumber(3)), ex.NewList(ex.NewSymbol("c"), ex.NewNumber(4))))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 295 assoc-remove of absent key returns equal list
+	res, err = Execute("(define alist '((a 1) ((b) 2))) (equal? alist (assoc-remove alist 'b))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewT()), true, "test#"+strconv.Itoa(test))
+
+	test++ // 296 assoc-remove of not association list
+	res, err = Execute("(assoc-remove '(1 2) 1)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 297 partition of numbers into evens and odds
+	res, err = Execute(`(call-with-values
+  (lambda () (partition (lambda (x) (= (car (cdr (floor/ x 2))) 0)) '(1 2 3 4 5 6 7)))
+  (lambda (evens odds) (cons evens (cons odds nil))))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewList(ex.NewNumber(2), ex.NewNumber(4), ex.NewNumber(6)),
+		ex.NewList(ex.NewNumber(1), ex.NewNumber(3), ex.NewNumber(5), ex.NewNumber(7)))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 298 partition of empty list
+	res, err = Execute("(partition number? nil)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNil(), ex.NewNil())), true, "test#"+strconv.Itoa(test))
+
+	test++ // 299 partition with not a function
+	res, err = Execute("(partition 1 '(1 2))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 300 group-by parity keeps order of groups and elements
+	res, err = Execute("(group-by (lambda (x) (car (cdr (floor/ x 2)))) '(3 4 1 6 5 2))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(
+		ex.NewList(ex.NewNumber(1), ex.NewList(ex.NewNumber(3), ex.NewNumber(1), ex.NewNumber(5))),
+		ex.NewList(ex.NewNumber(0), ex.NewList(ex.NewNumber(4), ex.NewNumber(6), ex.NewNumber(2))))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 301 group-by with list keys
+	res, err = Execute("(group-by (lambda (x) (take x 1)) '((a 1) (b 2) (a 3)))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(
+		ex.NewList(ex.NewList(ex.NewSymbol("a")), ex.NewList(ex.NewList(ex.NewSymbol("a"), ex.NewNumber(1)), ex.NewList(ex.NewSymbol("a"), ex.NewNumber(3)))),
+		ex.NewList(ex.NewList(ex.NewSymbol("b")), ex.NewList(ex.NewList(ex.NewSymbol("b"), ex.NewNumber(2)))))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 302 group-by of not a list
+	res, err = Execute("(group-by identity 1)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 303 delete-duplicates keeps the first occurrences
+	res, err = Execute("(delete-duplicates '(1 a 2 1 (3) a (3) 2))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(1), ex.NewSymbol("a"), ex.NewNumber(2), ex.NewList(ex.NewNumber(3)))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 304 delete-duplicates of list without repeats
+	res, err = Execute("(delete-duplicates '(1 2 3))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(1), ex.NewNumber(2), ex.NewNumber(3))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 305 delete-duplicates with custom equality
+	res, err = Execute("(delete-duplicates '(1 2 -1 3 -2 4) (lambda (a b) (= (* a a) (* b b))))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(1), ex.NewNumber(2), ex.NewNumber(3), ex.NewNumber(4))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 306 delete-duplicates with custom equality of empty list
+	res, err = Execute("(delete-duplicates nil =)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNil()), true, "test#"+strconv.Itoa(test))
+
+	test++ // 307 delete-duplicates of not a list
+	res, err = Execute("(delete-duplicates 1)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 308 string-pad-left and string-pad-right of short string
+	res, err = Execute(`(cons (string-pad-left '|ab| 4) (cons (string-pad-right '|ab| 4) nil))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewSymbol("  ab"), ex.NewSymbol("ab  "))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 309 string-pad-left and string-pad-right truncate long string
+	res, err = Execute(`(cons (string-pad-left '|привет| 3) (cons (string-pad-right '|привет| 3) (cons (string-pad-left '|ab| 0) nil)))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewSymbol("вет"), ex.NewSymbol("при"), ex.NewSymbol(""))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 310 string-pad-left and string-pad-right with custom pad character
+	res, err = Execute(`(cons (string-pad-left '|7| 3 '|0|) (cons (string-pad-right '|7| 3 '|ж|) nil))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewSymbol("007"), ex.NewSymbol("7жж"))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 311 string-pad-left with string as pad character
+	res, err = Execute(`(string-pad-left '|ab| 3 '|xy|)`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 312 string-pad-right with negative width
+	res, err = Execute(`(string-pad-right '|ab| -1)`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 313 string-repeat
+	res, err = Execute(`(string-repeat '|ab| 3)`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("ababab")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 314 string-repeat zero times
+	res, err = Execute(`(string-repeat '|ab| 0)`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 315 string-repeat with negative count
+	res, err = Execute(`(string-repeat '|ab| -2)`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 316 string-ref of ASCII symbol
+	res, err = Execute("(string-ref '|hello| 1)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("e")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 317 string-ref of multibyte symbol
+	res, err = Execute("(string-ref '|привет| 5)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("т")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 318 string-ref out of range
+	res, err = Execute("(string-ref '|привет| 6)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 319 char->string of character from string-ref
+	res, err = Execute("(+ (char->string (string-ref '|жук| 0)) '|!|)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("ж!")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 320 char->string of not a character
+	res, err = Execute("(char->string '|ab|)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 321 apply with list and leading arguments
+	res, err = Execute("(cons (apply + '(1 2 3)) (cons (apply - 10 '(1 2)) nil))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(6), ex.NewNumber(7))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 322 apply doesn't evaluate elements of list
+	res, err = Execute("(apply cons '((a b) (c)))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewList(ex.NewSymbol("a"), ex.NewSymbol("b")), ex.NewSymbol("c"))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 323 long loop of mutually recursive functions through apply
+	res, err = Execute(`
+(define even (lambda (n) (if (= n 0) T (apply odd (- n 1) nil))))
+(define odd (lambda (n) (if (= n 0) nil (apply even (cons (- n 1) nil)))))
+(cons (even 100000) (odd 100000))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewT().Cons(ex.NewNil())), true, "test#"+strconv.Itoa(test))
+
+	test++ // 324 apply with not a list
+	res, err = Execute("(apply + 1 2)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 325 backtrace of error in nested user functions
+	res, err = Execute(`
+(define inner (lambda (x) (car x)))
+(define outer (lambda (y) (+ 1 (inner y))))
+(outer 5)`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")) && strings.Join(res.Output.Backtrace(), " ") == "inner outer" &&
+		strings.Contains(res.Stderr, "in inner\nin outer\n"), true, "test#"+strconv.Itoa(test))
+
+	test++ // 326 backtrace of error in deep recursion is limited
+	res, err = Execute("(define f (lambda (n) (if (= n 0) (car 1) (+ 1 (f (- n 1)))))) (f 100)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")) && len(res.Output.Backtrace()) == 11 && res.Output.Backtrace()[10] == "...", true, "test#"+strconv.Itoa(test))
+
+	test++ // 327 #t is truthy and #f is nil
+	res, err = Execute("(cons (if #t 'yes 'no) (cons (if #f 'yes 'no) (cons #t #f)))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewSymbol("yes"), ex.NewSymbol("no"), ex.NewT())), true, "test#"+strconv.Itoa(test))
+
+	test++ // 328 vector literal
+	res, err = Execute("(define v #(1 (a b) |c d|)) (cons (vector? v) (cons (vector-ref v 1) (cons (vector-ref v 2) nil)))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewT(), ex.NewList(ex.NewSymbol("a"), ex.NewSymbol("b")), ex.NewSymbol("c d"))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 329 elements of vector literal aren't evaluated
+	res, err = Execute("#((+ 1 2) x #())")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewVector(ex.NewList(ex.NewSymbol("+"), ex.NewNumber(1), ex.NewNumber(2)), ex.NewSymbol("x"), ex.NewVector())), true, "test#"+strconv.Itoa(test))
+
+	test++ // 330 format with ~s of vector literal
+	res, err = Execute("(format '|~s| #(a |b c| (d)))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("#(|a| |b c| (|d|))")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 331 0 and empty symbol are true by default
+	res, err = Execute("(cons (if 0 'yes 'no) (cons (if '|| 'yes 'no) (cons (not 0) (cons (or 0 1) nil))))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewSymbol("yes"), ex.NewSymbol("yes"), ex.NewNil(), ex.NewNumber(0))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 332 0 and empty symbol are false with FalsyZeroAndEmpty option
+	res, err = ExecuteWithOptions("(cons (if 0 'yes 'no) (cons (if '|| 'yes 'no) (cons (not 0) (cons (or 0 '|| 1) (cons (and 1 0 2) nil)))))", Options{FalsyZeroAndEmpty: true})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewSymbol("no"), ex.NewSymbol("no"), ex.NewT(), ex.NewNumber(1), ex.NewNumber(0))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 333 cond and filtering functions with FalsyZeroAndEmpty option
+	res, err = ExecuteWithOptions("(cons (cond (0 'zero) ('|| 'empty) (T 'other)) (partition (lambda (x) x) '(1 0 2)))", Options{FalsyZeroAndEmpty: true})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("other").Cons(ex.NewList(ex.NewNumber(1), ex.NewNumber(2)).Cons(ex.NewList(ex.NewNumber(0)).ToList()))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 334 global symbol shadowed by argument and by local definition after global lookups
+	res, err = Execute(`
+(define x 'global)
+(define get (lambda () x))
+(define first (get))
+(define arg (lambda (x) x))
+(define local (lambda () (define x 'local) x))
+(cons first (cons (arg 'argument) (cons (local) (cons (get) nil))))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewSymbol("global"), ex.NewSymbol("argument"), ex.NewSymbol("local"), ex.NewSymbol("global"))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 335 unset! and set! of global symbol after global lookups
+	res, err = Execute("(define z 1) (define get (lambda () z)) (get) (set! z 2) (define before (get)) (unset! z) (cons before (bound? z))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(2).Cons(ex.NewNil())), true, "test#"+strconv.Itoa(test))
+
+	test++ // 336 set! of arguments, optional arguments and definitions in body of closure
+	res, err = Execute(`
+(define f (lambda (a b #!optional (c 3)) (set! a 10) (define d 4) (+ a b c d)))
+(define h (lambda args (set! args (cdr args)) args))
+(cons (f 1 2) (h 1 2 3))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(19).Cons(ex.NewList(ex.NewNumber(2), ex.NewNumber(3)))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 337 unset! of argument of closure
+	res, err = Execute("(define a 'global) (define g (lambda (a b) (unset! a) (cons a (cons b nil)))) (g 1 2)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewSymbol("global"), ex.NewNumber(2))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 338 symbols of program are eq?
+	res, err = Execute("(define l '(1)) (cons (eq? 'a (car (cdr '(b a)))) (cons (eq? 'ab (+ 'a 'b)) (cons (equal? 'ab (+ 'a 'b)) (cons (eq? '(1) '(1)) (cons (eq? l l) (cons (eq? 2 2) (cons (eq? nil '()) nil)))))))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewT(), ex.NewNil(), ex.NewT(), ex.NewNil(), ex.NewT(), ex.NewT(), ex.NewT())), true, "test#"+strconv.Itoa(test))
+
+	test++ // 339 two reads of the same symbol are eq?
+	expr, err = ExecuteTo("(eq? (car (read)) (car (read)))", ioutil.Discard, ioutil.Discard, strings.NewReader("foo\nfoo\n"))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, expr.Equal(ex.NewT()), true, "test#"+strconv.Itoa(test))
+
+	test++ // 340 constant folding calculates arithmetic of literals
+	res, err = ExecuteWithOptions("(define f (lambda (x) (* x (+ 2 (* 3 4))))) (cons (f 2) (cons (car '(+ 2 3)) nil))", Options{FoldConstants: true})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(28).Cons(ex.NewSymbol("+").ToList())), true, "test#"+strconv.Itoa(test))
+
+	test++ // 341 constant folding leaves non-constant expressions, quoted data and errors untouched
+	prog, _ := parser.NewParser("(f (+ 2 (* 3 4)) (+ x 1) '(+ 1 2) (/ 1 0) (+ 'a 'b) (g (- 5 1)))").Parse()
+	folded, _ := parser.NewParser("(f 14 (+ x 1) '(+ 1 2) (/ 1 0) (+ 'a 'b) (g 4))").Parse()
+	ir := newInterpreter(prog, ioutil.Discard, ioutil.Discard, os.Stdin)
+	assert.Equal(t, ir.foldProgram(prog).Equal(folded), true, "test#"+strconv.Itoa(test))
+
+	// arguments of macros, not calculated arguments of special forms, calls without arguments and rebound functions
+	for _, program := range []string{
+		"(defmacro m (x) (cons 'quote (cons x nil))) (m (+ 1 2))",
+		"(case 3 ((+ 1 2) 'yes) (else 'no))",
+		"(lambda (+) 1)",
+		"(define f (lambda (* x) (* 2 3)))",
+		"(define + -) (+ 5 1)",
+	} {
+		prog, _ = parser.NewParser(program).Parse()
+		assert.Equal(t, ir.foldProgram(prog).Equal(prog), true, program)
+	}
+
+	test++ // 342 folded error is reported on execution
+	res, err = ExecuteWithOptions("(define f (lambda () (/ 1 0))) 'defined", Options{FoldConstants: true})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("defined")), true, "test#"+strconv.Itoa(test))
+
+	// meaning of program isn't changed by folding
+	res, err = ExecuteWithOptions(`
+(defmacro m (x) (cons 'quote (cons x nil)))
+(define f (lambda (+) (+ 5 1)))
+(cons (m (+ 1 2)) (cons (case 3 ((+ 1 2) 'yes) (else 'no)) (cons (f -) nil)))`, Options{FoldConstants: true})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewList(ex.NewSymbol("+"), ex.NewNumber(1), ex.NewNumber(2)),
+		ex.NewSymbol("no"), ex.NewNumber(4))), true, "test#"+strconv.Itoa(test))
+
+	res, err = ExecuteWithOptions("(define + -) (+ 5 1)", Options{FoldConstants: true})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(4)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 343 letrec loop counting to 1000000
+	res, err = Execute("(letrec ((loop (lambda (i) (if (= i 1000000) i (loop (+ i 1)))))) (loop 0))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(1000000)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 344 mutually recursive closures of letrec*
+	res, err = Execute(`
+(letrec* ((even (lambda (n) (if (= n 0) T (odd (- n 1)))))
+          (odd (lambda (n) (if (= n 0) nil (even (- n 1))))))
+  (cons (even 100001) (cons (odd 100001) nil)))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNil(), ex.NewT())), true, "test#"+strconv.Itoa(test))
+
+	test++ // 345 local helpers defined in body of lambda
+	res, err = Execute(`
+(define f (lambda (x)
+  (define double (lambda (y) (* y 2)))
+  (define even (lambda (n) (if (= n 0) T (odd (- n 1)))))
+  (define odd (lambda (n) (if (= n 0) nil (even (- n 1)))))
+  (cons (double x) (cons (even x) nil))))
+(cons (f 3) (cons (bound? double) (cons (bound? even) nil)))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewList(ex.NewNumber(6), ex.NewNil()), ex.NewNil(), ex.NewNil())), true, "test#"+strconv.Itoa(test))
+
+	test++ // 346 local definition shadows global one only inside lambda
+	res, err = Execute("(define x 'global) (define f (lambda () (define x 'local) x)) (cons (f) (cons x nil))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewSymbol("local"), ex.NewSymbol("global"))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 347 mutually recursive top-level functions
+	res, err = Execute(`
+(define is-even (lambda (n) (if (= n 0) T (is-odd (- n 1)))))
+(define is-odd (lambda (n) (if (= n 0) nil (is-even (- n 1)))))
+(cons (is-even 10) (cons (is-odd 10) (cons (is-even 100001) nil)))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewT(), ex.NewNil(), ex.NewNil())), true, "test#"+strconv.Itoa(test))
+
+	test++ // 348 load-string with definition followed by its call
+	res, err = Execute("(load-string '|(define sq (lambda (x) (* x x))) (sq 7)|) (cons (sq 3) nil)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(9))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 349 load-string returns value of the last expression
+	res, err = Execute("(cons (load-string '|1 2 (+ 1 2)|) (cons (load-string '||) nil))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(3), ex.NewNil())), true, "test#"+strconv.Itoa(test))
+
+	test++ // 350 load-string of unfinished expression
+	res, err = Execute("(load-string '|(+ 1|)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 351 modules with same-named functions don't collide
+	res, err = Execute(`
+(module a (define f (lambda (x) (+ x 1))))
+(module b (define f (lambda (x) (* x 10))))
+(cons ((module-ref a f) 1) (cons ((module-ref b f) 1) (cons (bound? f) nil)))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(2), ex.NewNumber(10), ex.NewNil())), true, "test#"+strconv.Itoa(test))
+
+	test++ // 352 import binds symbols of module in the current scope
+	res, err = Execute(`
+(define x 5)
+(module a (define x 1) (define get-x (lambda () x)))
+(define f (lambda () (import a) (+ x (get-x))))
+(cons (f) (cons x nil))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(2), ex.NewNumber(5))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 353 module doesn't see local symbols of the caller
+	res, err = Execute("((lambda (y) (module a (define z y))) 1)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 354 references to undefined modules and symbols
+	res, err = Execute("(module a (define f 1)) (cons (catch (module-ref a g) (default error_description)) (cons (catch (import b) (default error_description)) nil))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewSymbol("module-ref: symbol 'g' is not defined in module 'a'"),
+		ex.NewSymbol("import: module 'b' is not defined"))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 355 parameter inside and outside parameterize
+	res, err = Execute(`
+(define p (make-parameter 1))
+(define f (lambda () (p)))
+(cons (f) (cons (parameterize ((p 2)) (cons (f) (parameterize ((p 3)) (cons (f) nil)))) (cons (f) nil)))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(1), ex.NewList(ex.NewNumber(2), ex.NewNumber(3)), ex.NewNumber(1))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 356 value of parameter is restored after error in body of parameterize
+	res, err = Execute("(define p (make-parameter 'a)) (cons (catch (parameterize ((p 'b)) (throw (p))) (default (p))) (cons (p) nil))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewSymbol("a"), ex.NewSymbol("a"))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 357 parameterize of not parameter
+	res, err = Execute("(parameterize ((car 1)) 2)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 358 call/cc for early exit from a loop
+	res, err = Execute(`
+(define find-big (lambda (l)
+  (call/cc (lambda (return)
+    (map (lambda (x) (if (> x 2) (return x))) l)
+    'none))))
+(cons (find-big '(1 2 3 4)) (cons (find-big '(1 2)) nil))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(3), ex.NewSymbol("none"))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 359 escape isn't caught by default handler of catch and runs cleanup of dynamic-wind
+	res, err = Execute(`
+(define log nil)
+(define res (call-with-current-continuation (lambda (k)
+  (catch
+    (dynamic-wind (lambda () nil) (lambda () (k 5)) (lambda () (set! log 'after)))
+    (default 'caught)))))
+(cons res (cons log nil))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(5), ex.NewSymbol("after"))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 360 continuation can't be called after call/cc returned
+	res, err = Execute("(define saved nil) (call/cc (lambda (k) (set! saved k))) (saved 1)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 361 generator yielding three values is pulled until exhaustion
+	res, err = Execute(`
+(define g (generator (yield 'a) (yield 'b) (yield 'c)))
+(define a (generator-next g))
+(define b (generator-next g))
+(define c (generator-next g))
+(cons a (cons b (cons c (cons (eof-object? (generator-next g)) (cons (eof-object? (generator-next g)) nil)))))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewSymbol("a"), ex.NewSymbol("b"), ex.NewSymbol("c"), ex.NewT(), ex.NewT())), true, "test#"+strconv.Itoa(test))
+
+	test++ // 362 infinite generator
+	res, err = Execute(`
+(define naturals (generator
+  (define loop (lambda (i) (yield i) (loop (+ i 1))))
+  (loop 0)))
+(generator-next naturals)
+(generator-next naturals)
+(generator-next naturals)`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(2)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 363 yield outside of generator
+	res, err = Execute("(define g (generator (yield 1))) (generator-next g) (yield 2)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 364 match of list against destructuring pattern and wildcard
+	res, err = Execute(`
+(define f (lambda (x)
+  (match x
+    (('point x y) (+ x y))
+    ((a b . rest) (cons a (cons b (cons rest nil))))
+    (() 'empty)
+    (_ 'other))))
+(cons (f '(point 2 3)) (cons (f '(1 2 3 4)) (cons (f nil) (cons (f '(1)) (cons (f 5) nil)))))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(5),
+		ex.NewList(ex.NewNumber(1), ex.NewNumber(2), ex.NewList(ex.NewNumber(3), ex.NewNumber(4))),
+		ex.NewSymbol("empty"), ex.NewSymbol("other"), ex.NewSymbol("other"))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 365 variables of pattern are bound in a new scope
+	res, err = Execute("(define a 1) (cons (match '(5 6) ((a 6) a)) (cons a nil))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(5), ex.NewNumber(1))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 366 no clause matches
+	res, err = Execute("(match 3 (1 'one) (2 'two))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 367 case-lambda dispatches by number of arguments
+	res, err = Execute(`
+(define area (case-lambda
+  ((side) (* side side))
+  ((width height) (* width height))
+  (rest (cons 'many rest))))
+(cons (area 3) (cons (area 2 5) (cons (area 1 2 3) nil)))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(9), ex.NewNumber(10),
+		ex.NewList(ex.NewSymbol("many"), ex.NewNumber(1), ex.NewNumber(2), ex.NewNumber(3)))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 368 no clause of case-lambda accepts arguments
+	res, err = Execute("((case-lambda ((x) x) ((x y) y)))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 369 threading macros are equivalent to nested calls
+	res, err = Execute(`
+(define inc (lambda (x) (+ x 1)))
+(cons (->> 5 inc (* 2) (- 30)) (cons (- 30 (* 2 (inc 5)))
+  (cons (-> 5 inc (* 2) (- 30)) (cons (- (* (inc 5) 2) 30) (cons (-> 7) nil)))))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(18), ex.NewNumber(18), ex.NewNumber(-18), ex.NewNumber(-18),
+		ex.NewNumber(7))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 370 threaded value is calculated once
+	res, err = Execute("(define n 0) (-> (begin (set! n (+ n 1)) n) (+ 1) (* 2)) n")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(1)), true, "test#"+strconv.Itoa(test))
+
+	test++ // 371 passed assert-equal
+	res, err = Execute("(assert-equal (cons 1 (cons (+ 1 1) nil)) '(1 2))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewT()), true, "test#"+strconv.Itoa(test))
+
+	test++ // 372 failed assert-equal describes both values
+	res, err = Execute("(catch (assert-equal (cons 1 nil) '(1 2)) (default error_description))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("assert-equal: expected (1 2), given (1)")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 373 run-tests reports passed and failed tests
+	res, err = Execute(`
+(deftest addition (assert-equal (+ 1 1) 2))
+(deftest broken (assert-equal (+ 1 1) 3))
+(run-tests)`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(1)) &&
+		res.Stdout == "PASS addition\nFAIL broken: assert-equal: expected 3, given 2\n2 tests, 1 passed, 1 failed\n", true, "test#"+strconv.Itoa(test))
+
+	test++ // 374 quasiquote with unquote and unquote-splicing
+	res, err = Execute("(define x 5) (define l '(1 2)) `(a ,x (b ,@l) ,(+ x 1) ,@l)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewSymbol("a"), ex.NewNumber(5),
+		ex.NewList(ex.NewSymbol("b"), ex.NewNumber(1), ex.NewNumber(2)), ex.NewNumber(6), ex.NewNumber(1), ex.NewNumber(2))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 375 quote prefix doesn't depend on binding of symbol quote
+	res, err = Execute("(cons ((lambda (quote) 'x) 1) (cons (car ''(1 2)) nil))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewSymbol("x"), ex.NewFunction("quote"))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 376 unquote-splicing of not list
+	res, err = Execute("`(1 ,@2)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 377 define-record-type with constructor, predicate, accessors and modifier
+	res, err = Execute(`
+(define-record-type point (make-point x y) point? (x point-x set-point-x!) (y point-y))
+(define p (make-point 1 2))
+(define x-before (point-x p))
+(set-point-x! p 10)
+(cons x-before (cons (point-x p) (cons (point-y p) (cons (point? p) (cons (point? '(1 2)) (cons (point? 5) nil))))))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(1), ex.NewNumber(10), ex.NewNumber(2), ex.NewT(), ex.NewNil(),
+		ex.NewNil())), true, "test#"+strconv.Itoa(test))
+
+	test++ // 378 records of different types with the same fields
+	res, err = Execute(`
+(define-record-type point (make-point x y) point? (x point-x) (y point-y))
+(define-record-type size (make-size x y) size? (x size-x) (y size-y))
+(cons (point? (make-size 1 2)) (cons (catch (point-x (make-size 1 2)) (default error_description)) nil))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNil(), ex.NewSymbol("point-x: expected point, given #<size 1 2>"))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 379 field of constructor must be defined
+	res, err = Execute("(define-record-type point (make-point x z) point? (x point-x))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 380 reflection of record
+	res, err = Execute(`
+(define-record-type point (make-point x y) point? (x point-x) (y point-y) (label point-label))
+(define p (make-point 1 2))
+(cons (record-type-name p) (cons (record-fields p) (cons (record? p) (cons (record? '(point 1 2)) nil))))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewSymbol("point"),
+		ex.NewList(ex.NewList(ex.NewSymbol("x"), ex.NewNumber(1)), ex.NewList(ex.NewSymbol("y"), ex.NewNumber(2)),
+			ex.NewList(ex.NewSymbol("label"), ex.NewNil())),
+		ex.NewT(), ex.NewNil())), true, "test#"+strconv.Itoa(test))
+
+	test++ // 381 reflection of not record
+	res, err = Execute("(record-fields '(1 2))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewFatal("")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 382 depth of tracing is restored after error in traced call
+	res, err = Execute(`
+(define f (lambda (x) (if (= x 0) (throw 'boom) (f (- x 1)))))
+(define g (lambda (x) x))
+(trace f)
+(trace g)
+(catch (f 1) (default nil))
+(g 5)`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(5)) && res.Stdout == "(f 1)\n  (f 0)\n(g 5)\n5\n", true, "test#"+strconv.Itoa(test))
+
+	test++ // 383 vector of arguments compared by equal? element-wise
+	res, err = Execute(`
+(cons (vector) (cons (equal? (vector 1 2) (vector 1 2)) (cons (equal? (vector 1 2) (vector 1 3))
+  (cons (equal? (cons (vector 1 '(a)) nil) (cons (vector (+ 0 1) (cons 'a nil)) nil)) nil))))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewVector(), ex.NewT(), ex.NewNil(), ex.NewT())), true, "test#"+strconv.Itoa(test))
+
+	test++ // 384 generator resumes after yield, so side effects of its expressions happen once
+	res, err = Execute(`
+(define n 0)
+(define g (generator (write 'a) (set! n (+ n 1)) (yield n) (write 'b) (yield n)))
+(define x (generator-next g))
+(define y (generator-next g))
+(cons x (cons y (cons (eof-object? (generator-next g)) nil)))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(1), ex.NewNumber(1), ex.NewT())) && res.Stdout == "ab", true, "test#"+strconv.Itoa(test))
+
+	test++ // 385 values of generators are pulled from closures, nested generators and long loops
+	res, err = Execute(`
+(define naturals (generator
+  (define loop (lambda (i) (yield i) (loop (+ i 1))))
+  (loop 0)))
+(define squares (generator
+  (define loop (lambda () (define x (generator-next naturals)) (yield (* x x)) (loop)))
+  (loop)))
+(define pull (lambda (g n) (if (= n 1) (generator-next g) (begin (generator-next g) (pull g (- n 1))))))
+(define a (+ 1 (pull squares 3)))
+(cons a (cons (pull naturals 10000) nil))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(5), ex.NewNumber(10002))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 386 generator falling with an error is exhausted
+	res, err = Execute(`
+(define g (generator (yield 1) (car 1) (yield 2)))
+(define a (generator-next g))
+(define b (catch (generator-next g) (default error_description)))
+(cons a (cons b (cons (eof-object? (generator-next g)) nil)))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(1), ex.NewSymbol("car: object must be pair"), ex.NewT())), true, "test#"+strconv.Itoa(test))
+
+	test++ // 387 generator can't be pulled by itself
+	res, err = Execute(`
+(define g (generator (generator-next g)))
+(catch (generator-next g) (default error_description))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("generator-next: generator is already running")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 388 integers out of range of int64
+	res, err = Execute(`
+(define f (lambda (code) (catch (eval code) (default error_description))))
+(cons (f '(bitwise-and 1e19 1e19)) (cons (f '(bitwise-not 1e30)) (cons (f '(arithmetic-shift 1e19 1))
+  (cons (f '(integer->bits -1e19 64)) (cons (bitwise-not -9007199254740992) nil)))))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewSymbol("bitwise-and: integer is out of range, given 10000000000000000000"),
+		ex.NewSymbol("bitwise-not: integer is out of range, given 1000000000000000000000000000000"),
+		ex.NewSymbol("arithmetic-shift: integer is out of range, given 10000000000000000000"),
+		ex.NewSymbol("integer->bits: integer is out of range, given -10000000000000000000"), ex.NewNumber(9007199254740991))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 389 string-repeat with too long result
+	res, err = Execute("(cons (catch (string-repeat 'ab 1e18) (default error_description)) (cons (len (string-repeat '|| 1e18)) nil))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewSymbol("string-repeat: result is too long"), ex.NewNumber(0))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 390 string-pad-left and string-pad-right with too large width
+	res, err = Execute("(cons (catch (string-pad-left 'ab 1e18) (default error_description)) (cons (catch (string-pad-right 'ab 1e18) (default error_description)) nil))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewSymbol("string-pad-left: width is too large"),
+		ex.NewSymbol("string-pad-right: width is too large"))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 391 nested quasiquotes calculate only unquotes of the outer one
+	res, err = Execute("(cons `(1 `(2 ,(3 ,(+ 1 1)))) (cons `(1 `(2 ,,@(cons 3 (cons 4 nil)))) nil))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(
+		ex.NewList(ex.NewNumber(1), ex.NewList(ex.NewSymbol("quasiquote"), ex.NewList(ex.NewNumber(2),
+			ex.NewList(ex.NewSymbol("unquote"), ex.NewList(ex.NewNumber(3), ex.NewNumber(2)))))),
+		ex.NewList(ex.NewNumber(1), ex.NewList(ex.NewSymbol("quasiquote"), ex.NewList(ex.NewNumber(2),
+			ex.NewList(ex.NewSymbol("unquote"), ex.NewNumber(3), ex.NewNumber(4))))))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 392 quasiquote of vectors
+	res, err = Execute("(cons `#(1 ,(+ 1 1)) (cons `#(1 ,@(cons 2 (cons 3 nil)) 4) nil))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewVector(ex.NewNumber(1), ex.NewNumber(2)),
+		ex.NewVector(ex.NewNumber(1), ex.NewNumber(2), ex.NewNumber(3), ex.NewNumber(4)))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 393 unquote-splicing out of list
+	res, err = Execute("(catch `,@(cons 1 nil) (default error_description))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("unquote-splicing: expected in list")), true, "test#"+strconv.Itoa(test))
+
+	test++ // 394 round-to of numbers which scaled values overflow
+	res, err = Execute("(cons (round-to 1e300 10) (cons (round-to 0.1 400) (cons (round-to 0 400) (cons (round-to 123456789.123 17) (cons (round-to 2.345 2) nil)))))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(1e300), ex.NewNumber(0.1), ex.NewNumber(0),
+		ex.NewNumber(123456789.123), ex.NewNumber(2.35))), true, "test#"+strconv.Itoa(test))
+
+	test++ // 395 letrec calculates all expressions before bindings, letrec* binds in order
+	res, err = Execute(`
+(define a 'outer)
+(cons (letrec* ((a 1) (b a)) b)
+  (cons (letrec ((a 1) (b a)) b)
+    (cons (letrec* ((ev? (lambda (n) (if (= n 0) T (od? (- n 1)))))
+                    (od? (lambda (n) (if (= n 0) nil (ev? (- n 1))))))
+            (ev? 1001))
+      (cons (letrec ((ev? (lambda (n) (if (= n 0) T (od? (- n 1)))))
+                     (od? (lambda (n) (if (= n 0) nil (ev? (- n 1))))))
+              (ev? 1001))
+        (cons a nil)))))`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(1), ex.NewNil(), ex.NewNil(), ex.NewNil(),
+		ex.NewSymbol("outer"))), true, "test#"+strconv.Itoa(test))
+
+}
+
+func TestLibrarySnapshot(t *testing.T) {
+	file, err := ioutil.TempFile("", "lib")
+	assert.Equal(t, err, nil)
+	defer os.Remove(file.Name())
+
+	_, err = file.WriteString("(define x 1) (define get-x (lambda () x))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, file.Close(), nil)
+
+	lib, err := LoadLibrary(file.Name())
+	assert.Equal(t, err, nil)
+
+	snapshot := lib.Snapshot()
+
+	res, err := lib.Execute("(define y 2) (set! x 10) (unset! +) (get-x)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Equal(ex.NewNumber(10)), true)
+
+	lib.Restore(snapshot)
+
+	res, err = lib.Execute("(cons (bound? y) (cons (get-x) (cons (+ 1 2) nil)))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Equal(ex.NewList(ex.NewNil(), ex.NewNumber(1), ex.NewNumber(3))), true)
+
+	res, err = lib.Call("get-x")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Equal(ex.NewNumber(1)), true)
+
+	// data is copied, so changes of it don't change the snapshot
+	_, err = lib.Execute("(define xs (cons 1 (cons 2 nil))) (define v (list->vector (cons 0 nil)))")
+	assert.Equal(t, err, nil)
+	snapshot = lib.Snapshot()
+
+	for i := 0; i < 2; i++ {
+		res, err = lib.Execute("(set-car! xs 99) (vector-fill! v 99) (cons (car xs) (cons (vector-ref v 0) nil))")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, res.Equal(ex.NewList(ex.NewNumber(99), ex.NewNumber(99))), true)
+
+		lib.Restore(snapshot)
+
+		res, err = lib.Execute("(cons (car xs) (cons (vector-ref v 0) nil))")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, res.Equal(ex.NewList(ex.NewNumber(1), ex.NewNumber(0))), true)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	file, err := ioutil.TempFile("", "load")
+	assert.Equal(t, err, nil)
+	defer os.Remove(file.Name())
+
+	_, err = file.WriteString("(define sq (lambda (x) (* x x))) (define n 4) (sq n)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, file.Close(), nil)
+
+	res, err := Execute("(cons (load '|" + file.Name() + "|) (cons (sq 3) (cons n nil)))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(16), ex.NewNumber(9), ex.NewNumber(4))), true)
+
+	// definitions of the file are global even if it's loaded by a closure
+	res, err = Execute("(define n 1) (define f (lambda (n) (load '|" + file.Name() + "|) n)) (cons (f 7) (cons (sq n) nil))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(7), ex.NewNumber(16))), true)
+
+	res, err = Execute("(load '|" + file.Name() + ".missing|)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.String, "load: file '"+file.Name()+".missing' does not exist")
+
+	res, err = ExecuteWithOptions("(load '|"+file.Name()+"|)", Options{NoFileAccess: true})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.String, "load: access to files is forbidden")
+
+	feature, err := ioutil.TempFile("", "feature")
+	assert.Equal(t, err, nil)
+	defer os.Remove(feature.Name())
+
+	_, err = feature.WriteString("(set! loads (+ loads 1)) (define inc (lambda (x) (+ x 1))) (provide 'inc)")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, feature.Close(), nil)
+
+	// side effects of the file occur once
+	res, err = Execute("(define loads 0) (require 'inc '|" + feature.Name() + "|) (require 'inc '|" + feature.Name() + "|) " +
+		"(require 'inc) (cons loads (cons (inc 1) nil))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(1), ex.NewNumber(2))), true)
+
+	// provided features aren't loaded
+	res, err = Execute("(provide 'inc) (cons (require 'inc '|" + feature.Name() + "|) (cons (bound? inc) nil))")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewSymbol("inc"), ex.NewNil())), true)
+}
+
+func TestInternalFunctions(t *testing.T) {
+	for _, program := range []string{
+		"(%generator-stop 1)",
+		"(%make-record)",
+		"(%record? 1)",
+		"(%record-ref 1 2 3 4)",
+		"(%record-set! 1)",
+		"(%parameterize 1)",
+		"(%unparameterize 1)",
+		"(%continue 1)",
+		"(%end-continuation)",
+		"(%test-result)",
+		"(%tests-summary 1)",
+		"(%memo 1)",
+		"(%memo-set 1)",
+		"(%cond 1)",
+		"(%partition 1)",
+		"(%group-by 1)",
+		"(%delete-duplicates 1)",
+		"(%call-with-values 1)",
+		"(%promise-set 1)",
+		"(%trace 1)",
+		"(%trace-exit 1)",
+		"(%letrec-define 1)",
+	} {
+		res, err := Execute(program)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, res.Output.Type == ex.Fatal && strings.HasSuffix(res.Output.String, "is not defined"), true, program)
+	}
+}
+
+func BenchmarkGlobalLookup(b *testing.B) {
+	program := `
+(define limit 20000)
+(define step 1)
+(define outer (lambda (a)
+  (define inner (lambda (b)
+    (define loop (lambda (i acc)
+      (if (< i limit)
+        (loop (+ i step) (+ acc step))
+        acc)))
+    (loop 0 0)))
+  (inner a)))
+(outer 0)`
+
+	for i := 0; i < b.N; i++ {
+		res, err := Execute(program)
+		if err != nil || !res.Output.Equal(ex.NewNumber(20000)) {
+			b.Fatal("unexpected result")
+		}
+	}
+}
+
+func TestCompileClosures(t *testing.T) {
+	options := Options{CompileClosures: true}
+
+	// compiled closures calculate the same results
+	res, err := ExecuteWithOptions(`
+(define fib (lambda (n) (if (< n 2) n (+ (fib (- n 1)) (fib (- n 2))))))
+(define pick (lambda (x) (and (number? x) (or (< x 0) (quote positive)))))
+(define shadowed (lambda (if) (if 1 2)))
+(cons (fib 15) (cons (pick 5) (cons (pick 'a) (cons (shadowed +) nil))))`, options)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(610), ex.NewSymbol("positive"), ex.NewNil(), ex.NewNumber(3))), true)
+
+	// closures with side effects are calculated by interpreter once
+	res, err = ExecuteWithOptions(`
+(define n 0)
+(define f (lambda (x) (if (< x 1) (begin (set! n (+ n 1)) (write x) n) (f (- x 1)))))
+(f 3) (f 2)`, options)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewNumber(2)) && res.Stdout == "00", true)
+
+	// deep tail and not tail recursion
+	res, err = ExecuteWithOptions(`
+(define loop (lambda (i) (if (< i 100000) (loop (+ i 1)) i)))
+(define count (lambda (n) (if (= n 0) 0 (+ 1 (count (- n 1))))))
+(cons (loop 0) (cons (count 20000) nil))`, options)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewList(ex.NewNumber(100000), ex.NewNumber(20000))), true)
+
+	// errors are reported by interpreter
+	res, err = ExecuteWithOptions(`
+(define f (lambda (x) (car x)))
+(define g (lambda (x) (+ 1 (f x))))
+(catch (g 1) (default error_description))`, options)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Equal(ex.NewSymbol("car: object must be pair")), true)
+
+	res, err = ExecuteWithOptions("(define f (lambda (x) (car x))) (f 1)", options)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Output.Type == ex.Fatal && res.Output.Backtrace()[0] == "f", true)
+}
+
+func BenchmarkClosureCalls(b *testing.B) {
+	benchmarkFib(b, Options{})
+}
+
+func BenchmarkCompiledClosureCalls(b *testing.B) {
+	benchmarkFib(b, Options{CompileClosures: true})
+}
+
+func benchmarkFib(b *testing.B, options Options) {
+	program := `
+(define fib (lambda (n)
+  (if (< n 2)
+    n
+    (+ (fib (- n 1)) (fib (- n 2))))))
+(fib 18)`
+
+	for i := 0; i < b.N; i++ {
+		res, err := ExecuteWithOptions(program, options)
+		if err != nil || !res.Output.Equal(ex.NewNumber(2584)) {
+			b.Fatal("unexpected result")
+		}
+	}
+}
+
+func BenchmarkSymbols(b *testing.B) {
+	program := `
+(define syms '(alpha beta gamma delta alpha beta gamma delta epsilon))
+(define loop (lambda (i acc)
+  (if (< i 2000)
+    (loop (+ i 1) (if (equal? (car (delete-duplicates syms)) 'alpha) (+ acc 1) acc))
+    acc)))
+(loop 0 0)`
+
+	for i := 0; i < b.N; i++ {
+		res, err := Execute(program)
+		if err != nil || !res.Output.Equal(ex.NewNumber(2000)) {
+			b.Fatal("unexpected result")
+		}
+	}
 }
@@ -3,6 +3,7 @@ package lexer
 import (
 	"fmt"
 	"math"
+	"strconv"
 	"unicode"
 )
 
@@ -14,8 +15,26 @@ const (
 	TagQuote
 	TagComma
 	TagEOF
+	TagTrue
+	TagFalse
+	TagVectorLPar
+	TagBackquote
+	TagCommaAt
 )
 
+// radixes are bases of integer literals by their prefixes: #x1F, #o17, #b1010
+var radixes = map[rune]int{
+	'x': 16,
+	'o': 8,
+	'b': 2,
+}
+
+// booleans are tags of boolean literals #t and #f
+var booleans = map[rune]int{
+	't': TagTrue,
+	'f': TagFalse,
+}
+
 type Coords struct {
 	Cursor, Line, Column int
 }
@@ -97,8 +116,32 @@ func (l *Lexer) NextToken() (*Token, error) {
 		res = l.token(TagRPar)
 	case '\'':
 		res = l.token(TagQuote)
+	case '`':
+		res = l.token(TagBackquote)
 	case ',':
+		if l.text[l.coords.Cursor+1] == '@' {
+			l.moveCursor()
+			res = l.token(TagCommaAt)
+			break
+		}
+
 		res = l.token(TagComma)
+	case '#':
+		if _, ok := radixes[unicode.ToLower(l.text[l.coords.Cursor+1])]; ok {
+			return l.parseRadixNumber()
+		}
+
+		if tok := l.parseBoolean(); tok != nil {
+			return tok, nil
+		}
+
+		if l.text[l.coords.Cursor+1] == '(' {
+			l.moveCursor()
+			res = l.token(TagVectorLPar)
+			break
+		}
+
+		return l.parseSymbolOrNumber()
 	default:
 		return l.parseSymbolOrNumber()
 	}
@@ -209,6 +252,56 @@ func (l *Lexer) parseSymbolOrNumber() (*Token, error) {
 	return l.parseSymbol(start)
 }
 
+// parseBoolean returns token of #t or #f literal or nil if there is no one at the cursor
+func (l *Lexer) parseBoolean() *Token {
+	if l.coords.Cursor+2 >= len(l.text) {
+		return nil
+	}
+
+	tag, ok := booleans[l.text[l.coords.Cursor+1]]
+	if !ok {
+		return nil
+	}
+
+	if c := l.text[l.coords.Cursor+2]; !unicode.IsSpace(c) && c != '(' && c != ')' {
+		return nil
+	}
+
+	l.moveCursor()
+	l.moveCursor()
+
+	return l.token(tag)
+}
+
+func (l *Lexer) parseRadixNumber() (*Token, error) {
+	l.moveCursor()
+	prefix := unicode.ToLower(l.getCurrentChar())
+	l.moveCursor()
+
+	sign := 1.0
+	if l.getCurrentChar() == '-' {
+		sign = -1.0
+		l.moveCursor()
+	}
+
+	start := l.coords.Cursor
+	for !l.isWSOrPar() {
+		l.moveCursor()
+	}
+
+	digits := string(l.text[start:l.coords.Cursor])
+	if digits == "" {
+		return nil, l.lexError(fmt.Sprintf("expected digits after '#%c'", prefix))
+	}
+
+	num, err := strconv.ParseUint(digits, radixes[prefix], 64)
+	if err != nil {
+		return nil, l.lexError(fmt.Sprintf("invalid literal '#%c%s'", prefix, digits))
+	}
+
+	return l.tokenNumber(TagNumber, sign*float64(num)), nil
+}
+
 func (l *Lexer) parseSymbol(start int) (*Token, error) {
 	for !l.isWSOrPar() {
 		l.moveCursor()
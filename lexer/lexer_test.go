@@ -55,3 +55,96 @@ func TestLexer(t *testing.T) {
 	tok, _ = lx.NextToken()
 	assert.Equal(t, tok.Tag, TagEOF)
 }
+
+func TestLexerRadix(t *testing.T) {
+	lx := NewLexer("#x1F #o17 #b1010 #X-ff (#b1) #xyz")
+	tok, _ := lx.NextToken()
+	assert.Equal(t, tok.Tag, TagNumber)
+	assert.Equal(t, tok.Number, 31.0)
+	tok, _ = lx.NextToken()
+	assert.Equal(t, tok.Tag, TagNumber)
+	assert.Equal(t, tok.Number, 15.0)
+	tok, _ = lx.NextToken()
+	assert.Equal(t, tok.Tag, TagNumber)
+	assert.Equal(t, tok.Number, 10.0)
+	tok, _ = lx.NextToken()
+	assert.Equal(t, tok.Tag, TagNumber)
+	assert.Equal(t, tok.Number, -255.0)
+	tok, _ = lx.NextToken()
+	assert.Equal(t, tok.Tag, TagLPar)
+	tok, _ = lx.NextToken()
+	assert.Equal(t, tok.Tag, TagNumber)
+	assert.Equal(t, tok.Number, 1.0)
+	tok, _ = lx.NextToken()
+	assert.Equal(t, tok.Tag, TagRPar)
+	_, err := lx.NextToken()
+	assert.Equal(t, err != nil, true)
+
+	for _, text := range []string{"#b102", "#o8", "#x"} {
+		_, err = NewLexer(text).NextToken()
+		assert.Equal(t, err != nil, true, text)
+	}
+}
+
+func TestLexerBoolean(t *testing.T) {
+	lx := NewLexer("#t #f (#t) #true #f1")
+	tok, _ := lx.NextToken()
+	assert.Equal(t, tok.Tag, TagTrue)
+	tok, _ = lx.NextToken()
+	assert.Equal(t, tok.Tag, TagFalse)
+	tok, _ = lx.NextToken()
+	assert.Equal(t, tok.Tag, TagLPar)
+	tok, _ = lx.NextToken()
+	assert.Equal(t, tok.Tag, TagTrue)
+	tok, _ = lx.NextToken()
+	assert.Equal(t, tok.Tag, TagRPar)
+	tok, _ = lx.NextToken()
+	assert.Equal(t, tok.Tag, TagSymbol)
+	assert.Equal(t, tok.String, "#true")
+	tok, _ = lx.NextToken()
+	assert.Equal(t, tok.Tag, TagSymbol)
+	tok, _ = lx.NextToken()
+	assert.Equal(t, tok.Tag, TagEOF)
+}
+
+func TestLexerVector(t *testing.T) {
+	lx := NewLexer("#(1 #(a)) #a")
+	tok, _ := lx.NextToken()
+	assert.Equal(t, tok.Tag, TagVectorLPar)
+	tok, _ = lx.NextToken()
+	assert.Equal(t, tok.Tag, TagNumber)
+	tok, _ = lx.NextToken()
+	assert.Equal(t, tok.Tag, TagVectorLPar)
+	tok, _ = lx.NextToken()
+	assert.Equal(t, tok.Tag, TagSymbol)
+	tok, _ = lx.NextToken()
+	assert.Equal(t, tok.Tag, TagRPar)
+	tok, _ = lx.NextToken()
+	assert.Equal(t, tok.Tag, TagRPar)
+	tok, _ = lx.NextToken()
+	assert.Equal(t, tok.Tag, TagSymbol)
+	tok, _ = lx.NextToken()
+	assert.Equal(t, tok.Tag, TagEOF)
+}
+
+func TestLexerQuasiquote(t *testing.T) {
+	lx := NewLexer("`(a ,b ,@c)")
+	tok, _ := lx.NextToken()
+	assert.Equal(t, tok.Tag, TagBackquote)
+	tok, _ = lx.NextToken()
+	assert.Equal(t, tok.Tag, TagLPar)
+	tok, _ = lx.NextToken()
+	assert.Equal(t, tok.Tag, TagSymbol)
+	tok, _ = lx.NextToken()
+	assert.Equal(t, tok.Tag, TagComma)
+	tok, _ = lx.NextToken()
+	assert.Equal(t, tok.Tag, TagSymbol)
+	tok, _ = lx.NextToken()
+	assert.Equal(t, tok.Tag, TagCommaAt)
+	tok, _ = lx.NextToken()
+	assert.Equal(t, tok.Tag, TagSymbol)
+	tok, _ = lx.NextToken()
+	assert.Equal(t, tok.Tag, TagRPar)
+	tok, _ = lx.NextToken()
+	assert.Equal(t, tok.Tag, TagEOF)
+}
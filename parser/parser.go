@@ -28,12 +28,16 @@ func (pe *ParseError) Error() string {
 
 // PROGRAM ::= INNER eof
 // LIST    ::= ( INNER )
+// VECTOR  ::= #( INNER )
 // INNER   ::= ELEM INNER | .
-// ELEM    ::= ' ELEM | , ELEM | number | symbol | LIST
+// ELEM    ::= ' ELEM | ` ELEM | , ELEM | ,@ ELEM | number | symbol | #t | #f | LIST | VECTOR
 
 type Parser struct {
 	curToken *lexer.Token
 	lexer    *lexer.Lexer
+
+	// quasiquotes is a number of quasiquotes around the current element, commas inside them are unquotes
+	quasiquotes int
 }
 
 func NewParser(text string) *Parser {
@@ -82,6 +86,27 @@ func (p *Parser) parseList() (*ex.Expr, error) {
 	return res, nil
 }
 
+// VECTOR ::= #( INNER )
+func (p *Parser) parseVector() (*ex.Expr, error) {
+	err := p.expect(lexer.TagVectorLPar)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := p.parseInner()
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.expect(lexer.TagRPar)
+	if err != nil {
+		return nil, err
+	}
+
+	elems, _ := res.ToSlice()
+	return ex.NewVector(elems...), nil
+}
+
 // INNER ::= ELEM INNER | .
 func (p *Parser) parseInner() (*ex.Expr, error) {
 	if p.curToken.Tag != lexer.TagRPar && p.curToken.Tag != lexer.TagEOF {
@@ -106,29 +131,24 @@ func (p *Parser) parseInner() (*ex.Expr, error) {
 	return ex.NewNil(), nil
 }
 
-// ELEM ::= ' ELEM | , ELEM | number | symbol | LIST
+// ELEM ::= ' ELEM | ` ELEM | , ELEM | ,@ ELEM | number | symbol | #t | #f | LIST | VECTOR
 func (p *Parser) parseElem() (*ex.Expr, error) {
 	var res *ex.Expr
 
 	switch p.curToken.Tag {
 	case lexer.TagQuote:
-		err := p.expect(lexer.TagQuote)
-		if err != nil {
-			return nil, err
-		}
-
-		expr, err := p.parseElem()
-		if err != nil {
-			return nil, err
-		}
-
-		res := ex.NewFunction("quote").Cons(expr.ToList())
-		if res.Type == ex.Fatal {
-			return nil, ex.NewExprError(res.String)
+		// quote is the function itself, so rebinding of symbol quote doesn't change quoted data
+		return p.parsePrefixed(lexer.TagQuote, ex.NewFunction("quote"), 0)
+	case lexer.TagBackquote:
+		return p.parsePrefixed(lexer.TagBackquote, ex.Intern("quasiquote"), 1)
+	case lexer.TagCommaAt:
+		return p.parsePrefixed(lexer.TagCommaAt, ex.Intern("unquote-splicing"), -1)
+	case lexer.TagComma:
+		if p.quasiquotes > 0 {
+			return p.parsePrefixed(lexer.TagComma, ex.Intern("unquote"), -1)
 		}
 
-		return res, nil
-	case lexer.TagComma:
+		// outside of quasiquote comma marks arguments of macro that are calculated
 		err := p.expect(lexer.TagComma)
 		if err != nil {
 			return nil, err
@@ -139,15 +159,26 @@ func (p *Parser) parseElem() (*ex.Expr, error) {
 			return nil, err
 		}
 
+		// interned symbols are shared, so the marked symbol is a copy
+		if expr.Type == ex.Symbol {
+			expr = ex.NewSymbol(expr.String)
+		}
+
 		expr.CalculatedForMacro = true
 
 		return expr, nil
 	case lexer.TagNumber:
 		res = ex.NewNumber(p.curToken.Number)
 	case lexer.TagSymbol:
-		res = ex.NewSymbol(p.curToken.String)
+		res = ex.Intern(p.curToken.String)
+	case lexer.TagTrue:
+		res = ex.NewT()
+	case lexer.TagFalse:
+		res = ex.NewNil()
 	case lexer.TagLPar:
 		return p.parseList()
+	case lexer.TagVectorLPar:
+		return p.parseVector()
 	default:
 		return nil, NewParseErr(p.curToken.Tag, -1, "multi unexpected", p.curToken.Coords)
 	}
@@ -160,6 +191,24 @@ func (p *Parser) parseElem() (*ex.Expr, error) {
 	return res, nil
 }
 
+// parsePrefixed parses element after prefix token and returns list of head and the element, e.g. `x is (quasiquote x).
+// Depth is added to number of quasiquotes around the element
+func (p *Parser) parsePrefixed(tag int, head *ex.Expr, depth int) (*ex.Expr, error) {
+	err := p.expect(tag)
+	if err != nil {
+		return nil, err
+	}
+
+	p.quasiquotes += depth
+	expr, err := p.parseElem()
+	p.quasiquotes -= depth
+	if err != nil {
+		return nil, err
+	}
+
+	return ex.NewList(head, expr), nil
+}
+
 func (p *Parser) expect(expected int) error {
 	if p.curToken.Tag != expected {
 		return NewParseErr(p.curToken.Tag, expected, "unexpected", p.curToken.Coords)
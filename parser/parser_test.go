@@ -3,6 +3,7 @@ package parser
 import (
 	"testing"
 
+	ex "github.com/batrSens/LispXS/expressions"
 	"github.com/magiconair/properties/assert"
 )
 
@@ -11,6 +12,44 @@ func TestParser(t *testing.T) {
 	debugT(t, "() nil 2 (+ 2 3) \"end\" (cons 8 '(3 4))")
 }
 
+func TestParserBoolean(t *testing.T) {
+	res, err := NewParser("#t (#f 1)").Parse()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Equal(ex.NewList(ex.NewT(), ex.NewList(ex.NewNil(), ex.NewNumber(1)))), true)
+}
+
+func TestParserVector(t *testing.T) {
+	res, err := NewParser("#(1 (a b) #()) #(").Parse()
+	assert.Equal(t, res, (*ex.Expr)(nil))
+	assert.Equal(t, err != nil, true)
+
+	res, err = NewParser("#(1 (a b) #())").Parse()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Equal(ex.NewList(ex.NewVector(ex.NewNumber(1), ex.NewList(ex.NewSymbol("a"), ex.NewSymbol("b")), ex.NewVector()))), true)
+}
+
+func TestParserQuote(t *testing.T) {
+	// quote is the function, so it doesn't depend on binding of symbol quote
+	res, err := NewParser("'(1 2)").Parse()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Equal(ex.NewList(ex.NewFunction("quote"), ex.NewList(ex.NewNumber(1), ex.NewNumber(2))).ToList()), true)
+
+	expected, err := NewParser("(quote (1 2))").Parse()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Equal(expected), false)
+
+	res, err = NewParser("`(a ,b ,@c)").Parse()
+	assert.Equal(t, err, nil)
+	expected, err = NewParser("(quasiquote (a (unquote b) (unquote-splicing c)))").Parse()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Equal(expected), true)
+
+	// outside of quasiquote comma marks symbol instead of unquote
+	res, err = NewParser(",a").Parse()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Car().Type == ex.Symbol && res.Car().CalculatedForMacro, true)
+}
+
 func debugT(t *testing.T, text string) {
 	prs := NewParser(text)
 